@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"time"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+)
+
+// Code generation directives.
+//
+//go:generate -command mapper lxd-generate db mapper -t client_certificates.mapper.go
+//go:generate mapper reset
+//
+//go:generate mapper stmt -e internal_client_certificate objects table=internal_client_certificates
+//go:generate mapper stmt -e internal_client_certificate objects-by-Name table=internal_client_certificates
+//go:generate mapper stmt -e internal_client_certificate objects-by-Fingerprint table=internal_client_certificates
+//go:generate mapper stmt -e internal_client_certificate id table=internal_client_certificates
+//go:generate mapper stmt -e internal_client_certificate create table=internal_client_certificates
+//go:generate mapper stmt -e internal_client_certificate delete-by-Name table=internal_client_certificates
+//
+//go:generate mapper method -e internal_client_certificate ID table=internal_client_certificates
+//go:generate mapper method -e internal_client_certificate Exists table=internal_client_certificates
+//go:generate mapper method -e internal_client_certificate GetOne table=internal_client_certificates
+//go:generate mapper method -e internal_client_certificate GetMany table=internal_client_certificates
+//go:generate mapper method -e internal_client_certificate Create table=internal_client_certificates
+//go:generate mapper method -e internal_client_certificate DeleteOne-by-Name table=internal_client_certificates
+
+// InternalClientCertificate is the database representation of the certificate of a trusted client.
+type InternalClientCertificate struct {
+	ID          int
+	Name        string `db:"primary=yes"`
+	Fingerprint string
+	Certificate string
+	CreatedAt   time.Time
+}
+
+// InternalClientCertificateFilter is the filter struct for filtering results from generated methods.
+type InternalClientCertificateFilter struct {
+	ID          *int
+	Name        *string
+	Fingerprint *string
+}
+
+// ToAPI returns the API representation of the trusted client.
+func (c InternalClientCertificate) ToAPI() internalTypes.ClientCertificate {
+	return internalTypes.ClientCertificate{
+		Name:        c.Name,
+		Fingerprint: c.Fingerprint,
+		CreatedAt:   c.CreatedAt,
+	}
+}
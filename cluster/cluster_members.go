@@ -50,6 +50,7 @@ type InternalClusterMember struct {
 	APIExtensions  extensions.Extensions
 	Heartbeat      time.Time
 	Role           Role
+	CreatedAt      time.Time
 }
 
 // InternalClusterMemberFilter is used for filtering queries using generated methods.
@@ -61,7 +62,7 @@ type InternalClusterMemberFilter struct {
 // ToAPI returns the api struct for a ClusterMember database entity.
 // The cluster member's status will be reported as unreachable by default.
 func (c InternalClusterMember) ToAPI() (*internalTypes.ClusterMember, error) {
-	address, err := types.ParseAddrPort(c.Address)
+	address, err := types.ParseHostPort(c.Address)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse address %q of database cluster member: %w", c.Address, err)
 	}
@@ -81,6 +82,7 @@ func (c InternalClusterMember) ToAPI() (*internalTypes.ClusterMember, error) {
 		SchemaInternalVersion: c.SchemaInternal,
 		SchemaExternalVersion: c.SchemaExternal,
 		LastHeartbeat:         c.Heartbeat,
+		CreatedAt:             c.CreatedAt,
 		Status:                internalTypes.MemberUnreachable,
 		Extensions:            c.APIExtensions,
 	}, nil
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitSQL splits a query into its statements on semicolons, except for those within string literals, quoted
+// identifiers, comments, or the body of a trigger. Terminated statements without any keywords are dropped.
+// Any statement left unterminated at the end of the query is returned as the remainder, which is empty if only
+// whitespace or comments follow the last semicolon.
+func SplitSQL(query string) (statements []string, remainder string) {
+	statements = []string{}
+	start := 0
+	words := 0
+	pending := false // Whether the current statement has anything other than whitespace and comments.
+	inTrigger := false
+	lastWord := ""
+
+	// skipTo moves past the next occurrence of the given terminator, or to the end of the query. It returns whether the
+	// terminator was found.
+	skipTo := func(i *int, terminator string) bool {
+		end := strings.Index(query[*i:], terminator)
+		if end < 0 {
+			*i = len(query)
+			return false
+		}
+
+		*i += end + len(terminator) - 1
+
+		return true
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Escaped quotes are doubled, so they are handled as two adjacent quoted strings.
+			pending = true
+			i++
+			skipTo(&i, string(c))
+		case c == '[':
+			pending = true
+			i++
+			skipTo(&i, "]")
+		case strings.HasPrefix(query[i:], "--"):
+			skipTo(&i, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i += 2
+			if !skipTo(&i, "*/") {
+				pending = true
+			}
+
+		case c == ';':
+			// The statements within a trigger body are separated by semicolons too, up to the final END.
+			if inTrigger && lastWord != "END" {
+				continue
+			}
+
+			if words > 0 {
+				statements = append(statements, strings.TrimSpace(query[start:i]))
+			}
+
+			start = i + 1
+			words = 0
+			pending = false
+			inTrigger = false
+			lastWord = ""
+		case c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			end := i
+			for end < len(query) && (query[end] == '_' || unicode.IsLetter(rune(query[end])) || unicode.IsDigit(rune(query[end]))) {
+				end++
+			}
+
+			lastWord = strings.ToUpper(query[i:end])
+			words++
+			pending = true
+
+			// The type of statement is given by its first few keywords, e.g. "CREATE TEMP TRIGGER".
+			if words <= 3 && lastWord == "TRIGGER" && strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query[start:i])), "CREATE") {
+				inTrigger = true
+			}
+
+			i = end - 1
+		case !unicode.IsSpace(rune(c)):
+			lastWord = ""
+			pending = true
+		}
+	}
+
+	if pending && start < len(query) {
+		remainder = strings.TrimSpace(query[start:])
+	}
+
+	return statements, remainder
+}
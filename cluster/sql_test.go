@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSQL(t *testing.T) {
+	cases := []struct {
+		query      string
+		statements []string
+		remainder  string
+	}{
+		{query: "SELECT 1; SELECT 2;", statements: []string{"SELECT 1", "SELECT 2"}},
+		{query: "SELECT 'a;b', \"c;d\", [e;f], `g;h`", statements: []string{}, remainder: "SELECT 'a;b', \"c;d\", [e;f], `g;h`"},
+		{query: "SELECT 'it''s;'; SELECT 2", statements: []string{"SELECT 'it''s;'"}, remainder: "SELECT 2"},
+		{query: "SELECT 1 -- one; two\n; /* three; */ SELECT 3", statements: []string{"SELECT 1 -- one; two"}, remainder: "/* three; */ SELECT 3"},
+		{query: " ; -- nothing\n;", statements: []string{}},
+		{query: "SELECT 1; -- done", statements: []string{"SELECT 1"}},
+		{
+			query:      "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x=1; DELETE FROM c; END; SELECT 1",
+			statements: []string{"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x=1; DELETE FROM c; END"},
+			remainder:  "SELECT 1",
+		},
+	}
+
+	for _, c := range cases {
+		statements, remainder := SplitSQL(c.query)
+		require.Equal(t, c.statements, statements, c.query)
+		require.Equal(t, c.remainder, remainder, c.query)
+	}
+}
+
+// Ensures statements entered a line at a time are only complete once their final semicolon is reached.
+func TestSplitSQLComplete(t *testing.T) {
+	cases := []struct {
+		text     string
+		complete bool
+	}{
+		{text: "SELECT 1;", complete: true},
+		{text: "SELECT 1", complete: false},
+		{text: "SELECT 1; -- comment", complete: true},
+		{text: "SELECT 'a;", complete: false},
+		{text: "SELECT [a;", complete: false},
+		{text: "SELECT 1; /* unterminated", complete: false},
+		{text: "-- comment;", complete: false},
+		{text: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\nUPDATE b SET x=1;", complete: false},
+		{text: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\nUPDATE b SET x=1;\nEND", complete: false},
+		{text: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\nUPDATE b SET x=1;\nEND;", complete: true},
+		{text: "CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x=1; END;", complete: true},
+	}
+
+	for _, c := range cases {
+		statements, remainder := SplitSQL(c.text)
+		require.Equal(t, c.complete, len(statements) > 0 && remainder == "", c.text)
+	}
+}
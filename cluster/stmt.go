@@ -1,18 +1,25 @@
 package cluster
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
+	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/shared/logger"
 )
 
 var stmtsByProject = map[string]map[int]string{} // Statement code to statement SQL text
-var preparedStmts = map[int]*sql.Stmt{}          // Statement code to SQL statement.
+
+// Mappers generated by lxd-generate only pass the transaction to Stmt, so the statements to use are recorded for each
+// transaction started by Transaction while it runs, and forgotten as soon as it ends.
+var txStmtsMu sync.RWMutex
+var txStmts = map[*sql.Tx]*Stmts{} // Running transaction to the statements prepared against its database.
 
 // RegisterStmt register a SQL statement.
 //
@@ -42,8 +49,14 @@ func RegisterStmt(sql string) int {
 	return code
 }
 
-// PrepareStmts prepares all registered statements and stores them in preparedStmts.
-func PrepareStmts(db *sql.DB, project string, skipErrors bool) error {
+// Stmts holds the registered statements prepared against a single database. It is kept by whoever owns the database,
+// so that several daemons can run in the same process.
+type Stmts struct {
+	prepared map[int]*sql.Stmt // Statement code to SQL statement.
+}
+
+// PrepareStmts prepares all registered statements against the given database.
+func PrepareStmts(db *sql.DB, project string, skipErrors bool) (*Stmts, error) {
 	logger.Infof("Preparing statements for Go project %q", project)
 
 	// Also prepare statements from microcluster if we are in a different project.
@@ -52,29 +65,90 @@ func PrepareStmts(db *sql.DB, project string, skipErrors bool) error {
 		projects = append(projects, project)
 	}
 
+	stmts := &Stmts{prepared: map[int]*sql.Stmt{}}
 	for _, project := range projects {
-		stmts := stmtsByProject[project]
-		for code, stmt := range stmts {
+		for code, stmt := range stmtsByProject[project] {
 			preparedStmt, err := db.Prepare(stmt)
 			if err != nil && !skipErrors {
-				return fmt.Errorf("%q: %w", stmt, err)
+				stmts.Close()
+
+				return nil, fmt.Errorf("%q: %w", stmt, err)
 			}
 
-			preparedStmts[code] = preparedStmt
+			if preparedStmt != nil {
+				stmts.prepared[code] = preparedStmt
+			}
 		}
 	}
 
-	return nil
+	return stmts, nil
+}
+
+// Close closes the prepared statements.
+func (s *Stmts) Close() {
+	if s == nil {
+		return
+	}
+
+	for _, stmt := range s.prepared {
+		_ = stmt.Close()
+	}
+}
+
+// Stmt returns the prepared statement with the given code for the transaction.
+// If the statement was not prepared, it is prepared for the transaction alone.
+func (s *Stmts) Stmt(tx *sql.Tx, code int) (*sql.Stmt, error) {
+	if s != nil {
+		stmt, ok := s.prepared[code]
+		if ok {
+			return tx.Stmt(stmt), nil
+		}
+	}
+
+	text, err := StmtString(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Prepare(text)
+}
+
+// Use runs the given function with the transaction, during which Stmt uses the given statements for it.
+// The statements must have been prepared against the database the transaction was started on.
+func (s *Stmts) Use(tx *sql.Tx, f func() error) error {
+	if s == nil {
+		return f()
+	}
+
+	txStmtsMu.Lock()
+	txStmts[tx] = s
+	txStmtsMu.Unlock()
+
+	defer func() {
+		txStmtsMu.Lock()
+		delete(txStmts, tx)
+		txStmtsMu.Unlock()
+	}()
+
+	return f()
+}
+
+// Transaction runs the given function in a transaction on the database, using the given statements, which must have
+// been prepared against that database.
+func Transaction(ctx context.Context, db *sql.DB, stmts *Stmts, f func(context.Context, *sql.Tx) error) error {
+	return query.Transaction(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+		return stmts.Use(tx, func() error { return f(ctx, tx) })
+	})
 }
 
 // Stmt prepares the in-memory prepared statement for the transaction.
+// If the transaction is not running through Transaction, the statement is prepared for the transaction alone.
 func Stmt(tx *sql.Tx, code int) (*sql.Stmt, error) {
-	stmt, ok := preparedStmts[code]
-	if !ok {
-		return nil, fmt.Errorf("No prepared statement registered with code %d", code)
-	}
+	txStmtsMu.RLock()
+	stmts := txStmts[tx]
+	txStmtsMu.RUnlock()
 
-	return tx.Stmt(stmt), nil
+	return stmts.Stmt(tx, code)
 }
 
 // StmtString returns the in-memory query string with the given code.
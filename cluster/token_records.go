@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"crypto/subtle"
 	"crypto/x509"
 
 	"github.com/canonical/lxd/shared"
@@ -42,7 +43,7 @@ type InternalTokenRecordFilter struct {
 }
 
 // ToAPI converts the InternalTokenRecord to a full token and returns an API compatible struct.
-func (t *InternalTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses []types.AddrPort) (*internalTypes.TokenRecord, error) {
+func (t *InternalTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses []types.HostPort) (*internalTypes.TokenRecord, error) {
 	token := internalTypes.Token{
 		Secret:        t.Secret,
 		Fingerprint:   shared.CertFingerprint(clusterCert),
@@ -59,3 +60,17 @@ func (t *InternalTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses
 		Name:  t.Name,
 	}, nil
 }
+
+// MatchInternalTokenRecord returns the record from the list whose secret matches the given secret, or nil if none do.
+// Secrets are compared in constant time, and every record is checked, so that the time taken does not reveal how much
+// of the secret was guessed correctly.
+func MatchInternalTokenRecord(records []InternalTokenRecord, secret string) *InternalTokenRecord {
+	var match *InternalTokenRecord
+	for i := range records {
+		if subtle.ConstantTimeCompare([]byte(records[i].Secret), []byte(secret)) == 1 {
+			match = &records[i]
+		}
+	}
+
+	return match
+}
@@ -1,11 +1,56 @@
 package config
 
 import (
+	"github.com/canonical/microcluster/internal/hooks"
+	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 )
 
+// HookType identifies a hook when configuring its policy.
+type HookType = types.HookType
+
+// The hooks that can be given a HookPolicy.
+const (
+	OnStart         = types.OnStart
+	PreBootstrap    = types.PreBootstrap
+	PostBootstrap   = types.PostBootstrap
+	PreJoin         = types.PreJoin
+	PostJoin        = types.PostJoin
+	PreRemove       = types.PreRemove
+	PostRemove      = types.PostRemove
+	OnNewMember     = types.OnNewMember
+	OnHeartbeat     = types.OnHeartbeat
+	OnJoinRejected  = types.OnJoinRejected
+	OnLeaderElected = types.OnLeaderElected
+	OnLeaderLost    = types.OnLeaderLost
+	PreShutdown     = types.PreShutdown
+)
+
+// HookPolicy configures the timeout, retries and failure handling of a hook, and whether it runs asynchronously.
+type HookPolicy = hooks.Policy
+
+// HookFailurePolicy decides what happens when a hook has failed every attempt.
+type HookFailurePolicy = hooks.FailurePolicy
+
+const (
+	// HookFailureAbort returns the error of a failed hook to the operation that ran it, which stops and reverts
+	// what it can. This is the default.
+	HookFailureAbort = hooks.FailureAbort
+
+	// HookFailureWarn logs the error of a failed hook and carries on with the operation.
+	HookFailureWarn = hooks.FailureWarn
+)
+
+// HookContext describes the cluster event that caused a hook to run, such as the member that joined or is being
+// removed, the member that initiated it, and the results of a heartbeat round.
+type HookContext = state.HookContext
+
 // Hooks holds customizable functions that can be called at varying points by the daemon to.
 // integrate with other tools.
+//
+// Each hook is followed by any executables in its directory under the state directory, such as hooks/post-join.d for
+// PostJoin, which run in lexical order. They receive a JSON description of the event on stdin, and the same details
+// in MICROCLUSTER_* environment variables. Their output is logged, and they are subject to the hook's policy.
 type Hooks struct {
 	// PreBootstrap is run before the daemon is initialized and bootstrapped.
 	PreBootstrap func(s *state.State, initConfig map[string]string) error
@@ -33,6 +78,40 @@ type Hooks struct {
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat func(s *state.State) error
 
+	// PreRemoveWithContext is run in place of PreRemove if set, and also receives the member being removed and the
+	// member removing it.
+	PreRemoveWithContext func(s *state.State, hookCtx HookContext) error
+
+	// PostRemoveWithContext is run in place of PostRemove if set, and also receives the member that was removed and
+	// the member that removed it.
+	PostRemoveWithContext func(s *state.State, hookCtx HookContext) error
+
+	// OnHeartbeatWithContext is run in place of OnHeartbeat if set, and also receives the results of the heartbeat
+	// round.
+	OnHeartbeatWithContext func(s *state.State, hookCtx HookContext) error
+
+	// OnLeaderElected is run when this cluster member becomes the dqlite leader.
+	OnLeaderElected func(s *state.State) error
+
+	// OnLeaderLost is run when this cluster member stops being the dqlite leader, or can no longer confirm that it is.
+	OnLeaderLost func(s *state.State) error
+
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
 	OnNewMember func(s *state.State) error
+
+	// OnNewMemberWithContext is run in place of OnNewMember if set, and also receives the member that joined.
+	OnNewMemberWithContext func(s *state.State, hookCtx HookContext) error
+
+	// OnJoinRejected is run on the cluster member that rejected a join attempt with an invalid token. It receives the
+	// requested member name, the address the attempt came from, and the number of consecutive failed attempts from
+	// that address.
+	OnJoinRejected func(s *state.State, name string, source string, failures int) error
+
+	// PreShutdown is run when the daemon is shutting down, after in-flight requests have completed and dqlite
+	// leadership has been handed over, but before the database is closed.
+	PreShutdown func(s *state.State) error
+
+	// Policies configure how each hook is run. Hooks without a policy run once, synchronously, without a timeout,
+	// and abort the operation that ran them if they fail.
+	Policies map[HookType]HookPolicy
 }
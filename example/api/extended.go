@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/client"
 	extendedTypes "github.com/canonical/microcluster/example/api/types"
@@ -35,15 +36,16 @@ func cmdPost(state *state.State, r *http.Request) response.Response {
 		}
 
 		messages := make([]string, 0, len(cluster))
-		err = cluster.Query(state.Context, true, func(ctx context.Context, c *client.Client) error {
-			addrPort, err := types.ParseAddrPort(state.Address().URL.Host)
+		// Use the request context so that each cluster member logs the forwarded request under the same request ID.
+		err = cluster.Query(rest.RequestContext(state.Context, r), true, func(ctx context.Context, c *client.Client) error {
+			hostPort, err := types.ParseHostPort(state.Address().URL.Host)
 			if err != nil {
 				return fmt.Errorf("Failed to parse addr:port of listen address %q: %w", state.Address().URL.Host, err)
 			}
 
 			// Our payload in this case is defined by us as ExtendedType.
 			data := &extendedTypes.ExtendedType{
-				Sender:  addrPort,
+				Sender:  hostPort,
 				Message: "Testing 1 2 3...",
 			}
 
@@ -78,6 +80,8 @@ func cmdPost(state *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	rest.Logger(r).Debug("Received extended request", logger.Ctx{"sender": info.Sender.String()})
+
 	// Return some identifying information.
 	message := fmt.Sprintf("cluster member at address %q received message %q from cluster member at address %q", state.Address().URL.Host, info.Message, info.Sender.String())
 
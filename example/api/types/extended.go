@@ -5,6 +5,6 @@ import "github.com/canonical/microcluster/rest/types"
 
 // ExtendedType is an example of an API type usable by MicroCluster but defined by this example project.
 type ExtendedType struct {
-	Sender  types.AddrPort `json:"sender" yaml:"sender"`
+	Sender  types.HostPort `json:"sender" yaml:"sender"`
 	Message string         `json:"message" yaml:"message"`
 }
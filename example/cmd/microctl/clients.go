@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+type cmdClients struct {
+	common *CmdControl
+}
+
+func (c *cmdClients) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clients",
+		Short: "Manage the clients outside of the cluster that are trusted to use its API.",
+		RunE:  c.run,
+	}
+
+	var cmdList = cmdClientsList{common: c.common}
+	cmd.AddCommand(cmdList.command())
+
+	var cmdRevoke = cmdClientsRevoke{common: c.common}
+	cmd.AddCommand(cmdRevoke.command())
+
+	return cmd
+}
+
+func (c *cmdClients) run(cmd *cobra.Command, args []string) error {
+	return cmd.Help()
+}
+
+type cmdClientsList struct {
+	common *CmdControl
+}
+
+func (c *cmdClientsList) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List trusted clients",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClientsList) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	clients, err := m.ListClients(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(clients))
+	for i, client := range clients {
+		data[i] = []string{client.Name, client.Fingerprint, client.CreatedAt.Format(time.RFC3339)}
+	}
+
+	header := []string{"NAME", "FINGERPRINT", "CREATED AT"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, clients)
+}
+
+type cmdClientsRevoke struct {
+	common *CmdControl
+}
+
+func (c *cmdClientsRevoke) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke <name>",
+		Short: "Revoke the trust of the client with the given name",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClientsRevoke) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	return m.RevokeClient(cmd.Context(), args[0])
+}
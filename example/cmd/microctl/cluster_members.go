@@ -1,13 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
+	"time"
 
 	cli "github.com/canonical/lxd/shared/cmd"
 	"github.com/spf13/cobra"
 
 	"github.com/canonical/microcluster/client"
-	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/cluster"
 )
 
 type cmdClusterMembers struct {
@@ -27,6 +30,12 @@ func (c *cmdClusterMembers) command() *cobra.Command {
 	var cmdList = cmdClusterMembersList{common: c.common}
 	cmd.AddCommand(cmdList.command())
 
+	var cmdCheck = cmdClusterMembersCheck{common: c.common}
+	cmd.AddCommand(cmdCheck.command())
+
+	var cmdAbort = cmdClusterMemberAbort{common: c.common}
+	cmd.AddCommand(cmdAbort.command())
+
 	return cmd
 }
 
@@ -54,7 +63,7 @@ func (c *cmdClusterMembersList) run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get all state information for MicroCluster.
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -81,13 +90,33 @@ func (c *cmdClusterMembersList) run(cmd *cobra.Command, args []string) error {
 
 	data := make([][]string, len(clusterMembers))
 	for i, clusterMember := range clusterMembers {
-		data[i] = []string{clusterMember.Name, clusterMember.Address.String(), clusterMember.Role, clusterMember.Certificate.String(), string(clusterMember.Status)}
+		role := clusterMember.Role
+		if role == string(cluster.Pending) {
+			role = fmt.Sprintf("%s (%s)", role, time.Since(clusterMember.CreatedAt).Truncate(time.Second))
+		}
+
+		var lastHeartbeat string
+		if !clusterMember.LastHeartbeat.IsZero() {
+			lastHeartbeat = clusterMember.LastHeartbeat.Format(time.RFC3339)
+		}
+
+		data[i] = []string{
+			clusterMember.Name,
+			clusterMember.Address.String(),
+			role,
+			clusterMember.Certificate.String(),
+			string(clusterMember.Status),
+			strconv.FormatUint(clusterMember.SchemaInternalVersion, 10),
+			strconv.FormatUint(clusterMember.SchemaExternalVersion, 10),
+			strconv.Itoa(clusterMember.Extensions.Version()),
+			lastHeartbeat,
+		}
 	}
 
-	header := []string{"NAME", "ADDRESS", "ROLE", "CERTIFICATE", "STATUS"}
+	header := []string{"NAME", "ADDRESS", "ROLE", "CERTIFICATE", "STATUS", "INTERNAL SCHEMA", "EXTERNAL SCHEMA", "EXTENSIONS", "LAST HEARTBEAT"}
 	sort.Sort(cli.SortColumnsNaturally(data))
 
-	return cli.RenderTable(cli.TableFormatTable, header, data, clusterMembers)
+	return cli.RenderTable(c.common.FlagFormat, header, data, clusterMembers)
 }
 
 type cmdClusterMemberRemove struct {
@@ -113,7 +142,7 @@ func (c *cmdClusterMemberRemove) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -130,3 +159,90 @@ func (c *cmdClusterMemberRemove) run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+type cmdClusterMembersCheck struct {
+	common *CmdControl
+
+	flagRepair bool
+}
+
+func (c *cmdClusterMembersCheck) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check that the truststore, database, and dqlite agree on the cluster members.",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().BoolVar(&c.flagRepair, "repair", false, "Repair any issues that are found")
+
+	return cmd
+}
+
+func (c *cmdClusterMembersCheck) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	report, err := client.CheckConsistency(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if c.flagRepair && len(report.Issues) > 0 {
+		report, err = client.RepairConsistency(cmd.Context(), report.Issues)
+		if err != nil {
+			return err
+		}
+	}
+
+	data := make([][]string, len(report.Issues))
+	for i, issue := range report.Issues {
+		data[i] = []string{string(issue.Type), issue.Name, issue.Address, issue.Message}
+	}
+
+	header := []string{"ISSUE", "NAME", "ADDRESS", "MESSAGE"}
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, report.Issues)
+}
+
+type cmdClusterMemberAbort struct {
+	common *CmdControl
+}
+
+func (c *cmdClusterMemberAbort) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abort <name>",
+		Short: "Abort the join of the pending cluster member with the given name.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClusterMemberAbort) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return client.AbortClusterMemberJoin(cmd.Context(), args[0])
+}
@@ -7,7 +7,6 @@ import (
 
 	microClient "github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/example/client"
-	"github.com/canonical/microcluster/microcluster"
 )
 
 type cmdExtended struct {
@@ -29,7 +28,7 @@ func (c *cmdExtended) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+type cmdHealth struct {
+	common *CmdControl
+}
+
+func (c *cmdHealth) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Show the health of each component of the daemon",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdHealth) run(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	health, err := m.Health(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// Machine-readable formats include the overall status in the rendered object instead.
+	if c.common.FlagFormat == cli.TableFormatJSON || c.common.FlagFormat == cli.TableFormatYAML {
+		return cli.RenderTable(c.common.FlagFormat, nil, nil, health)
+	}
+
+	fmt.Printf("Status: %s\n", health.Status)
+
+	data := make([][]string, len(health.Components))
+	for i, component := range health.Components {
+		data[i] = []string{component.Name, string(component.Status), component.Message}
+	}
+
+	header := []string{"COMPONENT", "STATUS", "MESSAGE"}
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, health.Components)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+type cmdHooks struct {
+	common *CmdControl
+}
+
+func (c *cmdHooks) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "List the most recent hook runs on the daemon",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdHooks) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	runs, err := m.HookRuns(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(runs))
+	for i, run := range runs {
+		mode := "sync"
+		if run.Async {
+			mode = "async"
+		}
+
+		duration := run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond)
+		data[i] = []string{string(run.Hook), run.Member, mode, run.StartedAt.Format(time.RFC3339), duration.String(), fmt.Sprint(run.Attempts), run.Error}
+	}
+
+	header := []string{"HOOK", "MEMBER", "MODE", "STARTED", "DURATION", "ATTEMPTS", "ERROR"}
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, runs)
+}
@@ -2,8 +2,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
+	cli "github.com/canonical/lxd/shared/cmd"
 	"github.com/spf13/cobra"
 
 	"github.com/canonical/microcluster/example/version"
@@ -19,6 +21,8 @@ type CmdControl struct {
 	FlagLogDebug   bool
 	FlagLogVerbose bool
 	FlagStateDir   string
+	FlagFormat     string
+	FlagRemote     string
 }
 
 func main() {
@@ -31,6 +35,14 @@ func main() {
 		Version:           version.Version,
 		SilenceUsage:      true,
 		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch commonCmd.FlagFormat {
+			case cli.TableFormatTable, cli.TableFormatCSV, cli.TableFormatJSON, cli.TableFormatYAML, cli.TableFormatCompact:
+				return nil
+			}
+
+			return fmt.Errorf("Invalid format %q, must be one of table, csv, json, yaml or compact", commonCmd.FlagFormat)
+		},
 	}
 
 	app.PersistentFlags().StringVar(&commonCmd.FlagStateDir, "state-dir", "", "Path to store state information"+"``")
@@ -38,6 +50,8 @@ func main() {
 	app.PersistentFlags().BoolVar(&commonCmd.FlagVersion, "version", false, "Print version number")
 	app.PersistentFlags().BoolVarP(&commonCmd.FlagLogDebug, "debug", "d", false, "Show all debug messages")
 	app.PersistentFlags().BoolVarP(&commonCmd.FlagLogVerbose, "verbose", "v", false, "Show all information messages")
+	app.PersistentFlags().StringVar(&commonCmd.FlagRemote, "remote", "", "Name of the remote to send commands to, instead of the default remote"+"``")
+	app.PersistentFlags().StringVar(&commonCmd.FlagFormat, "format", cli.TableFormatTable, "Format of listed output (table|csv|json|yaml|compact)"+"``")
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
@@ -59,9 +73,24 @@ func main() {
 	var cmdWaitready = cmdWaitready{common: &commonCmd}
 	app.AddCommand(cmdWaitready.command())
 
+	var cmdHealth = cmdHealth{common: &commonCmd}
+	app.AddCommand(cmdHealth.command())
+
 	var cmdExtended = cmdExtended{common: &commonCmd}
 	app.AddCommand(cmdExtended.command())
 
+	var cmdRemote = cmdRemote{common: &commonCmd}
+	app.AddCommand(cmdRemote.command())
+
+	var cmdClients = cmdClients{common: &commonCmd}
+	app.AddCommand(cmdClients.command())
+
+	var cmdTasks = cmdTasks{common: &commonCmd}
+	app.AddCommand(cmdTasks.command())
+
+	var cmdHooks = cmdHooks{common: &commonCmd}
+	app.AddCommand(cmdHooks.command())
+
 	app.InitDefaultHelpCmd()
 
 	err := app.Execute()
@@ -7,8 +7,6 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-
-	"github.com/canonical/microcluster/microcluster"
 )
 
 type cmdInit struct {
@@ -41,7 +39,7 @@ func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return fmt.Errorf("Unable to configure MicroCluster: %w", err)
 	}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/canonical/lxd/shared"
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// localRemote is the name of the remote for the daemon in the local state directory.
+const localRemote = "local"
+
+// remote is a cluster member that microctl can connect to over HTTPS.
+type remote struct {
+	Address     string `yaml:"address"`
+	ClusterCert string `yaml:"cluster_cert"`
+}
+
+// remoteConfig is the microctl client configuration, stored in ~/.config/microctl/config.yaml.
+type remoteConfig struct {
+	DefaultRemote string            `yaml:"default_remote"`
+	Remotes       map[string]remote `yaml:"remotes"`
+}
+
+// configDir returns the directory holding the microctl configuration and client keypair.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "microctl"), nil
+}
+
+// loadConfig reads the microctl configuration, returning an empty one if it doesn't exist yet.
+func loadConfig() (*remoteConfig, error) {
+	config := &remoteConfig{DefaultRemote: localRemote, Remotes: map[string]remote{}}
+
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse client configuration: %w", err)
+	}
+
+	if config.Remotes == nil {
+		config.Remotes = map[string]remote{}
+	}
+
+	return config, nil
+}
+
+// save writes the microctl configuration.
+func (c *remoteConfig) save() error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "config.yaml"), data, 0600)
+}
+
+// clientCert returns the keypair microctl authenticates to remotes with, generating it if it doesn't exist yet.
+func clientCert() (*shared.CertInfo, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return shared.KeyPairAndCA(dir, "client", shared.CertClient, false)
+}
+
+// App returns a MicroCluster for the daemon in the state directory, or connected to the selected remote.
+func (c *CmdControl) App() (*microcluster.MicroCluster, error) {
+	args := microcluster.Args{StateDir: c.FlagStateDir, Verbose: c.FlagLogVerbose, Debug: c.FlagLogDebug}
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	name := c.FlagRemote
+	if name == "" {
+		name = config.DefaultRemote
+	}
+
+	if name == "" || name == localRemote {
+		return microcluster.App(args)
+	}
+
+	r, ok := config.Remotes[name]
+	if !ok {
+		return nil, fmt.Errorf("No remote found with name %q", name)
+	}
+
+	clusterCert, err := types.ParseX509Certificate(r.ClusterCert)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cluster certificate for remote %q: %w", name, err)
+	}
+
+	args.ClientCert, err = clientCert()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load client certificate: %w", err)
+	}
+
+	args.StateDir = ""
+	args.ClusterCert = clusterCert.Certificate
+	m, err := microcluster.App(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send every request, including those usually sent over the control socket, to the remote.
+	args.Client, err = m.RemoteClient(r.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return microcluster.App(args)
+}
+
+type cmdRemote struct {
+	common *CmdControl
+}
+
+func (c *cmdRemote) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remote",
+		Short: "Manage the cluster members that microctl connects to over HTTPS.",
+		RunE:  c.run,
+	}
+
+	var cmdAdd = cmdRemoteAdd{common: c.common}
+	cmd.AddCommand(cmdAdd.command())
+
+	var cmdList = cmdRemoteList{common: c.common}
+	cmd.AddCommand(cmdList.command())
+
+	var cmdRemove = cmdRemoteRemove{common: c.common}
+	cmd.AddCommand(cmdRemove.command())
+
+	var cmdSwitch = cmdRemoteSwitch{common: c.common}
+	cmd.AddCommand(cmdSwitch.command())
+
+	return cmd
+}
+
+func (c *cmdRemote) run(cmd *cobra.Command, args []string) error {
+	return cmd.Help()
+}
+
+type cmdRemoteAdd struct {
+	common *CmdControl
+
+	flagClientName string
+}
+
+func (c *cmdRemoteAdd) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <name> <token>",
+		Short: "Add a remote, trusting this client with a join token issued under the client name.",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().StringVar(&c.flagClientName, "client-name", "", "Name the token was issued under. Defaults to the hostname")
+
+	return cmd
+}
+
+func (c *cmdRemoteAdd) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Help()
+	}
+
+	name, token := args[0], args[1]
+	if name == localRemote {
+		return fmt.Errorf("Remote name %q is reserved", localRemote)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	_, ok := config.Remotes[name]
+	if ok {
+		return fmt.Errorf("Remote %q already exists", name)
+	}
+
+	clientName := c.flagClientName
+	if clientName == "" {
+		clientName, err = os.Hostname()
+		if err != nil {
+			return fmt.Errorf("Failed to get hostname: %w", err)
+		}
+	}
+
+	cert, err := clientCert()
+	if err != nil {
+		return fmt.Errorf("Failed to load client certificate: %w", err)
+	}
+
+	m, err := microcluster.App(microcluster.Args{ClientCert: cert, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	if err != nil {
+		return err
+	}
+
+	address, clusterCert, err := m.TrustClient(cmd.Context(), clientName, token)
+	if err != nil {
+		return err
+	}
+
+	config.Remotes[name] = remote{Address: address, ClusterCert: types.X509Certificate{Certificate: clusterCert}.String()}
+
+	return config.save()
+}
+
+type cmdRemoteList struct {
+	common *CmdControl
+}
+
+func (c *cmdRemoteList) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the configured remotes.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdRemoteList) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	type remoteInfo struct {
+		Name        string `json:"name" yaml:"name"`
+		Address     string `json:"address" yaml:"address"`
+		Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+		Default     bool   `json:"default" yaml:"default"`
+	}
+
+	remotes := []remoteInfo{{Name: localRemote, Address: c.common.FlagStateDir, Default: config.DefaultRemote == localRemote}}
+	for name, r := range config.Remotes {
+		info := remoteInfo{Name: name, Address: r.Address, Default: config.DefaultRemote == name}
+
+		cert, err := types.ParseX509Certificate(r.ClusterCert)
+		if err == nil {
+			info.Fingerprint = shared.CertFingerprint(cert.Certificate)
+		}
+
+		remotes = append(remotes, info)
+	}
+
+	sort.Slice(remotes, func(i, j int) bool { return remotes[i].Name < remotes[j].Name })
+
+	data := make([][]string, len(remotes))
+	for i, r := range remotes {
+		name := r.Name
+		if r.Default {
+			name += " (default)"
+		}
+
+		data[i] = []string{name, r.Address, r.Fingerprint}
+	}
+
+	header := []string{"NAME", "ADDRESS", "FINGERPRINT"}
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, remotes)
+}
+
+type cmdRemoteRemove struct {
+	common *CmdControl
+}
+
+func (c *cmdRemoteRemove) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove the remote with the given name.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdRemoteRemove) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	_, ok := config.Remotes[args[0]]
+	if !ok {
+		return fmt.Errorf("No remote found with name %q", args[0])
+	}
+
+	delete(config.Remotes, args[0])
+	if config.DefaultRemote == args[0] {
+		config.DefaultRemote = localRemote
+	}
+
+	return config.save()
+}
+
+type cmdRemoteSwitch struct {
+	common *CmdControl
+}
+
+func (c *cmdRemoteSwitch) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "switch <name>",
+		Short: "Set the remote that commands are sent to by default.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdRemoteSwitch) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	_, ok := config.Remotes[args[0]]
+	if !ok && args[0] != localRemote {
+		return fmt.Errorf("No remote found with name %q", args[0])
+	}
+
+	config.DefaultRemote = args[0]
+
+	return config.save()
+}
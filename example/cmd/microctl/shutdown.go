@@ -2,8 +2,6 @@ package main
 
 import (
 	"github.com/spf13/cobra"
-
-	"github.com/canonical/microcluster/microcluster"
 )
 
 type cmdShutdown struct {
@@ -25,7 +23,7 @@ func (c *cmdShutdown) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -1,48 +1,76 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	cli "github.com/canonical/lxd/shared/cmd"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/microcluster"
 )
 
 type cmdSQL struct {
 	common *CmdControl
+
+	flagTransaction bool
+	flagReadOnly    bool
+	flagMaxRows     int
+	flagTimeout     time.Duration
+	flagTarget      string
 }
 
 func (c *cmdSQL) command() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "sql <query>",
+		Use:   "sql [<query>]",
 		Short: "Execute a SQL query against the daemon",
+		Long:  "Execute a SQL query against the daemon, or start an interactive SQL shell if no query is given.",
 		RunE:  c.run,
 	}
 
+	cmd.Flags().BoolVar(&c.flagTransaction, "transaction", false, "Run all statements in a single transaction")
+	cmd.Flags().BoolVar(&c.flagReadOnly, "read-only", false, "Refuse any statement that would write to the database")
+	cmd.Flags().IntVar(&c.flagMaxRows, "max-rows", 0, "Maximum number of rows to return for each statement")
+	cmd.Flags().DurationVar(&c.flagTimeout, "timeout", 0, "How long the query may run for")
+	cmd.Flags().StringVar(&c.flagTarget, "target", "", "Name of the cluster member to run the query on")
+
 	return cmd
 }
 
 func (c *cmdSQL) run(cmd *cobra.Command, args []string) error {
-	if len(args) != 1 {
+	if len(args) > 1 {
 		err := cmd.Help()
 		if err != nil {
 			return fmt.Errorf("Unable to load help: %w", err)
 		}
 
-		if len(args) == 0 {
-			return nil
-		}
+		return nil
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
 
-	query := args[0]
-	dump, batch, err := m.SQL(cmd.Context(), query)
+	opts := microcluster.SQLOptions{
+		Transaction: c.flagTransaction,
+		ReadOnly:    c.flagReadOnly,
+		MaxRows:     c.flagMaxRows,
+		Timeout:     c.flagTimeout,
+		Target:      c.flagTarget,
+	}
+
+	if len(args) == 0 {
+		return c.shell(cmd.Context(), m, opts)
+	}
+
+	dump, batch, err := m.SQLWithOptions(cmd.Context(), args[0], opts)
 	if err != nil {
 		return err
 	}
@@ -52,14 +80,27 @@ func (c *cmdSQL) run(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Machine-readable formats render the results of every statement as a single document.
+	format := c.common.FlagFormat
+	if format == cli.TableFormatJSON || format == cli.TableFormatYAML {
+		return cli.RenderTable(format, nil, nil, batch.Results)
+	}
+
 	for i, result := range batch.Results {
-		if len(batch.Results) > 1 {
+		if len(batch.Results) > 1 && format != cli.TableFormatCSV {
 			fmt.Printf("=> Query %d:\n\n", i)
 		}
 
 		if result.Type == "select" {
-			sqlPrintSelectResult(result.Columns, result.Rows)
-		} else {
+			err = sqlPrintResult(os.Stdout, format, result.Columns, result.Rows)
+			if err != nil {
+				return err
+			}
+
+			if result.Truncated {
+				fmt.Fprintf(os.Stderr, "Only the first %d rows are shown\n", c.flagMaxRows)
+			}
+		} else if format != cli.TableFormatCSV {
 			fmt.Printf("Rows affected: %d\n", result.RowsAffected)
 		}
 
@@ -67,15 +108,74 @@ func (c *cmdSQL) run(cmd *cobra.Command, args []string) error {
 			fmt.Printf("\n")
 		}
 	}
+
 	return nil
 }
 
-func sqlPrintSelectResult(columns []string, rows [][]any) {
-	table := tablewriter.NewWriter(os.Stdout)
+// sqlPrintResult writes the rows returned by a query in the given format, one of those accepted by --format.
+func sqlPrintResult(w io.Writer, format string, columns []string, rows [][]any) error {
+	switch format {
+	case cli.TableFormatCSV:
+		writer := csv.NewWriter(w)
+		err := writer.Write(columns)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			data := []string{}
+			for _, col := range row {
+				data = append(data, fmt.Sprintf("%v", col))
+			}
+
+			err = writer.Write(data)
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+	case cli.TableFormatJSON, cli.TableFormatYAML:
+		objects := make([]map[string]any, 0, len(rows))
+		for _, row := range rows {
+			object := make(map[string]any, len(columns))
+			for i, col := range row {
+				object[columns[i]] = col
+			}
+
+			objects = append(objects, object)
+		}
+
+		if format == cli.TableFormatYAML {
+			out, err := yaml.Marshal(objects)
+			if err != nil {
+				return err
+			}
+
+			_, err = w.Write(out)
+
+			return err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(objects)
+	}
+
+	table := tablewriter.NewWriter(w)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(false)
 	table.SetHeader(columns)
+	if format == cli.TableFormatCompact {
+		table.SetColumnSeparator("")
+		table.SetHeaderLine(false)
+		table.SetBorder(false)
+	}
+
 	for _, row := range rows {
 		data := []string{}
 		for _, col := range row {
@@ -86,4 +186,6 @@ func sqlPrintSelectResult(columns []string, rows [][]any) {
 	}
 
 	table.Render()
+
+	return nil
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"golang.org/x/term"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/microcluster"
+)
+
+const sqlShellHelp = `Statements are run once terminated with a semicolon, and may span several lines.
+
+.dump                  Print the contents of the database as SQL
+.exit, .quit           Exit the shell
+.help                  Show this message
+.mode [<format>]       Set or show the output format for query results (table|csv|json|yaml|compact)
+.schema [<table>]      Print the schema of the database, or of a single table
+.tables                List the tables in the database
+.timer [on|off]        Set or show whether the run time of each query is printed
+`
+
+// sqlShell is an interactive SQL prompt against the daemon.
+type sqlShell struct {
+	m    *microcluster.MicroCluster
+	opts microcluster.SQLOptions
+	out  io.Writer
+
+	mode  string
+	timer bool
+}
+
+// shell runs the interactive prompt until the input is exhausted or the user exits.
+func (c *cmdSQL) shell(ctx context.Context, m *microcluster.MicroCluster, opts microcluster.SQLOptions) error {
+	s := &sqlShell{m: m, opts: opts, out: os.Stdout, mode: c.common.FlagFormat}
+
+	var readLine func(prompt string) (string, error)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("Failed to configure terminal: %w", err)
+		}
+
+		defer func() { _ = term.Restore(fd, oldState) }()
+
+		// The terminal keeps a history of the entered lines, and translates newlines in the output for raw mode.
+		terminal := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "")
+
+		width, height, err := term.GetSize(fd)
+		if err == nil {
+			_ = terminal.SetSize(width, height)
+		}
+
+		s.out = terminal
+		readLine = func(prompt string) (string, error) {
+			terminal.SetPrompt(prompt)
+			return terminal.ReadLine()
+		}
+
+		fmt.Fprintf(s.out, "Enter \".help\" for usage hints, or press Ctrl-D to exit.\n")
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		readLine = func(string) (string, error) {
+			if !scanner.Scan() {
+				err := scanner.Err()
+				if err == nil {
+					err = io.EOF
+				}
+
+				return "", err
+			}
+
+			return scanner.Text(), nil
+		}
+	}
+
+	var statement strings.Builder
+	for {
+		prompt := "sql> "
+		if statement.Len() > 0 {
+			prompt = "...> "
+		}
+
+		line, err := readLine(prompt)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if statement.Len() == 0 {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, ".") {
+				exit, err := s.meta(ctx, line)
+				if err != nil {
+					fmt.Fprintf(s.out, "Error: %v\n", err)
+				}
+
+				if exit {
+					return nil
+				}
+
+				continue
+			}
+		} else {
+			statement.WriteString("\n")
+		}
+
+		statement.WriteString(line)
+		if sqlStatementComplete(statement.String()) {
+			s.run(ctx, statement.String())
+			statement.Reset()
+		}
+	}
+
+	// Run any unterminated statement left at the end of the input.
+	if strings.TrimSpace(statement.String()) != "" {
+		s.run(ctx, statement.String())
+	}
+
+	return nil
+}
+
+// run sends the query to the daemon and prints its results, or the error it failed with.
+func (s *sqlShell) run(ctx context.Context, query string) {
+	start := time.Now()
+	_, batch, err := s.m.SQLWithOptions(ctx, query, s.opts)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+		return
+	}
+
+	for _, result := range batch.Results {
+		if result.Type == "select" {
+			err = sqlPrintResult(s.out, s.mode, result.Columns, result.Rows)
+			if err != nil {
+				fmt.Fprintf(s.out, "Error: %v\n", err)
+				return
+			}
+
+			if result.Truncated {
+				fmt.Fprintf(s.out, "Only the first %d rows are shown\n", s.opts.MaxRows)
+			}
+		} else {
+			fmt.Fprintf(s.out, "Rows affected: %d\n", result.RowsAffected)
+		}
+	}
+
+	if s.timer {
+		fmt.Fprintf(s.out, "Run time: %s\n", time.Since(start))
+	}
+}
+
+// meta handles a dot-command, returning true if the shell should exit.
+func (s *sqlShell) meta(ctx context.Context, line string) (bool, error) {
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case ".exit", ".quit":
+		return true, nil
+	case ".help":
+		fmt.Fprint(s.out, sqlShellHelp)
+	case ".dump":
+		return false, s.dump(ctx, ".dump")
+	case ".schema":
+		if len(args) == 0 {
+			return false, s.dump(ctx, ".schema")
+		}
+
+		table := strings.ReplaceAll(args[0], "'", "''")
+		_, batch, err := s.m.SQLWithOptions(ctx, fmt.Sprintf("SELECT sql FROM sqlite_master WHERE tbl_name = '%s' AND sql IS NOT NULL ORDER BY type DESC, name", table), s.opts)
+		if err != nil {
+			return false, err
+		}
+
+		for _, result := range batch.Results {
+			for _, row := range result.Rows {
+				fmt.Fprintf(s.out, "%v;\n", row[0])
+			}
+		}
+	case ".tables":
+		s.run(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	case ".mode":
+		if len(args) == 0 {
+			fmt.Fprintf(s.out, "%s\n", s.mode)
+			return false, nil
+		}
+
+		switch args[0] {
+		case cli.TableFormatTable, cli.TableFormatCSV, cli.TableFormatJSON, cli.TableFormatYAML, cli.TableFormatCompact:
+			s.mode = args[0]
+		default:
+			return false, fmt.Errorf("Unknown mode %q, must be one of table, csv, json, yaml or compact", args[0])
+		}
+	case ".timer":
+		if len(args) == 0 {
+			fmt.Fprintf(s.out, "%v\n", s.timer)
+			return false, nil
+		}
+
+		switch args[0] {
+		case "on":
+			s.timer = true
+		case "off":
+			s.timer = false
+		default:
+			return false, fmt.Errorf("Expected \"on\" or \"off\", got %q", args[0])
+		}
+	default:
+		return false, fmt.Errorf("Unknown command %q, enter \".help\" for usage hints", command)
+	}
+
+	return false, nil
+}
+
+// dump prints the schema or full contents of the database.
+func (s *sqlShell) dump(ctx context.Context, query string) error {
+	dump, _, err := s.m.SQLWithOptions(ctx, query, s.opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(s.out, dump)
+
+	return nil
+}
+
+// sqlStatementComplete returns whether the text ends with a semicolon that isn't quoted, part of a comment, or within
+// the body of a trigger.
+func sqlStatementComplete(text string) bool {
+	statements, remainder := cluster.SplitSQL(text)
+
+	return len(statements) > 0 && remainder == ""
+}
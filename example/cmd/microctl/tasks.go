@@ -0,0 +1,111 @@
+package main
+
+import (
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+type cmdTasks struct {
+	common *CmdControl
+}
+
+func (c *cmdTasks) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "Manage background tasks of the MicroCluster daemon",
+		RunE:  c.run,
+	}
+
+	var cmdList = cmdTasksList{common: c.common}
+	cmd.AddCommand(cmdList.command())
+
+	var cmdTrigger = cmdTasksTrigger{common: c.common}
+	cmd.AddCommand(cmdTrigger.command())
+
+	return cmd
+}
+
+func (c *cmdTasks) run(cmd *cobra.Command, args []string) error {
+	return cmd.Help()
+}
+
+type cmdTasksList struct {
+	common *CmdControl
+}
+
+func (c *cmdTasksList) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List background tasks registered on the daemon, and when they last ran",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdTasksList) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	tasks, err := m.Tasks(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	}
+
+	data := make([][]string, len(tasks))
+	for i, task := range tasks {
+		scope := "all members"
+		if task.LeaderOnly {
+			scope = "leader"
+		}
+
+		data[i] = []string{task.Name, task.Schedule, scope, formatTime(task.LastRun), formatTime(task.NextRun), task.LastError}
+	}
+
+	header := []string{"NAME", "SCHEDULE", "RUNS ON", "LAST RUN", "NEXT RUN", "LAST ERROR"}
+
+	return cli.RenderTable(c.common.FlagFormat, header, data, tasks)
+}
+
+type cmdTasksTrigger struct {
+	common *CmdControl
+}
+
+func (c *cmdTasksTrigger) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trigger <name>",
+		Short: "Run the background task with the given name now",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdTasksTrigger) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.common.App()
+	if err != nil {
+		return err
+	}
+
+	return m.TriggerTask(cmd.Context(), args[0])
+}
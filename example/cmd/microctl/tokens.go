@@ -6,8 +6,6 @@ import (
 
 	cli "github.com/canonical/lxd/shared/cmd"
 	"github.com/spf13/cobra"
-
-	"github.com/canonical/microcluster/microcluster"
 )
 
 type cmdSecrets struct {
@@ -56,7 +54,7 @@ func (c *cmdTokensAdd) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -90,7 +88,7 @@ func (c *cmdTokensList) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -108,7 +106,7 @@ func (c *cmdTokensList) run(cmd *cobra.Command, args []string) error {
 	header := []string{"NAME", "TOKENS"}
 	sort.Sort(cli.SortColumnsNaturally(data))
 
-	return cli.RenderTable(cli.TableFormatTable, header, data, records)
+	return cli.RenderTable(c.common.FlagFormat, header, data, records)
 }
 
 type cmdTokensRevoke struct {
@@ -130,7 +128,7 @@ func (c *cmdTokensRevoke) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
-	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir, Verbose: c.common.FlagLogVerbose, Debug: c.common.FlagLogDebug})
+	m, err := c.common.App()
 	if err != nil {
 		return err
 	}
@@ -2,17 +2,23 @@
 package main
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 
 	"github.com/canonical/microcluster/config"
 	"github.com/canonical/microcluster/example/api"
 	"github.com/canonical/microcluster/example/database"
 	"github.com/canonical/microcluster/example/version"
 	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
 	"github.com/canonical/microcluster/state"
+	"github.com/canonical/microcluster/tasks"
 )
 
 // Debug indicates whether to log debug messages or not.
@@ -43,6 +49,18 @@ type cmdDaemon struct {
 
 	flagStateDir    string
 	flagSocketGroup string
+
+	flagLogFile   string
+	flagLogFormat string
+
+	flagTraceEndpoint string
+	flagTraceFile     string
+
+	flagOIDCIssuer   string
+	flagOIDCAudience string
+	flagOIDCJWKS     string
+
+	flagEmbedded bool
 }
 
 func (c *cmdDaemon) command() *cobra.Command {
@@ -59,7 +77,45 @@ func (c *cmdDaemon) command() *cobra.Command {
 }
 
 func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
-	m, err := microcluster.App(microcluster.Args{StateDir: c.flagStateDir, SocketGroup: c.flagSocketGroup, Verbose: c.global.flagLogVerbose, Debug: c.global.flagLogDebug})
+	// exampleHealthChecks are reported by the health endpoint alongside the built-in checks.
+	exampleHealthChecks := []rest.HealthCheck{
+		{
+			Name: "state_dir",
+			Check: func(ctx context.Context, s *state.State) error {
+				_, err := os.Stat(s.OS.StateDir)
+
+				return err
+			},
+		},
+	}
+
+	appArgs := microcluster.Args{StateDir: c.flagStateDir, SocketGroup: c.flagSocketGroup, Verbose: c.global.flagLogVerbose, Debug: c.global.flagLogDebug, LogFile: c.flagLogFile, LogFormat: c.flagLogFormat, Embedded: c.flagEmbedded, TraceEndpoint: c.flagTraceEndpoint, HealthChecks: exampleHealthChecks}
+
+	// Spans can also be written to a file, which is useful for testing without a collector.
+	if c.flagTraceFile != "" {
+		f, err := os.OpenFile(c.flagTraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		appArgs.TraceExporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Requests can also be authenticated by an OpenID Connect token.
+	if c.flagOIDCIssuer != "" {
+		appArgs.Authenticators = append(appArgs.Authenticators, access.NewOIDCAuthenticator(access.OIDCConfig{
+			Issuer:   c.flagOIDCIssuer,
+			Audience: c.flagOIDCAudience,
+			JWKS:     c.flagOIDCJWKS,
+		}))
+	}
+
+	m, err := microcluster.App(appArgs)
 	if err != nil {
 		return err
 	}
@@ -118,7 +174,17 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 		OnStart: func(s *state.State) error {
 			logger.Info("This is a hook that runs after the daemon first starts")
 
-			return nil
+			// Tasks registered here run in the background for as long as the daemon is running.
+			return s.Tasks().Register(tasks.Task{
+				Name:       "example",
+				Schedule:   tasks.Every(time.Hour),
+				LeaderOnly: true,
+				Run: func(ctx context.Context) error {
+					logger.Infof("This is a task that runs every hour on the dqlite leader, currently %q", s.Name())
+
+					return nil
+				},
+			})
 		},
 
 		// PostJoin is run after the daemon is initialized and joins a cluster.
@@ -147,9 +213,9 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
-		// PostRemove is run after the daemon is removed from a cluster.
-		PostRemove: func(s *state.State, force bool) error {
-			logger.Infof("This is a hook that is run on peer %q after a cluster member is removed, with the force flag set to %v", s.Name(), force)
+		// PostRemoveWithContext is run after the daemon is removed from a cluster.
+		PostRemoveWithContext: func(s *state.State, hookCtx config.HookContext) error {
+			logger.Infof("This is a hook that is run on peer %q after %q removed cluster member %q, with the force flag set to %v", s.Name(), hookCtx.Initiator, hookCtx.Member.Name, hookCtx.Force)
 
 			return nil
 		},
@@ -161,19 +227,59 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
-		// OnHeartbeat is run after a successful heartbeat round.
-		OnHeartbeat: func(s *state.State) error {
-			logger.Info("This is a hook that is run on the dqlite leader after a successful heartbeat")
+		// OnHeartbeatWithContext is run after a successful heartbeat round.
+		OnHeartbeatWithContext: func(s *state.State, hookCtx config.HookContext) error {
+			logger.Infof("This is a hook that is run on the dqlite leader after a successful heartbeat of %d cluster members", len(hookCtx.Heartbeat))
 
 			return nil
 		},
 
-		// OnNewMember is run after a new member has joined.
-		OnNewMember: func(s *state.State) error {
-			logger.Infof("This is a hook that is run on peer %q when a new cluster member has joined", s.Name())
+		// OnLeaderElected is run when this member becomes the dqlite leader.
+		OnLeaderElected: func(s *state.State) error {
+			logger.Infof("This is a hook that is run on peer %q when it becomes the dqlite leader", s.Name())
 
 			return nil
 		},
+
+		// OnLeaderLost is run when this member stops being the dqlite leader.
+		OnLeaderLost: func(s *state.State) error {
+			logger.Infof("This is a hook that is run on peer %q when it stops being the dqlite leader", s.Name())
+
+			return nil
+		},
+
+		// OnNewMemberWithContext is run after a new member has joined.
+		OnNewMemberWithContext: func(s *state.State, hookCtx config.HookContext) error {
+			logger.Infof("This is a hook that is run on peer %q when new cluster member %q has joined", s.Name(), hookCtx.Member.Name)
+
+			return nil
+		},
+
+		// OnJoinRejected is run after a join attempt with an invalid token is rejected.
+		OnJoinRejected: func(s *state.State, name string, source string, failures int) error {
+			logger.Warnf("This is a hook that is run on peer %q after rejecting join attempt %d for %q from %q", s.Name(), failures, name, source)
+
+			return nil
+		},
+
+		// PreShutdown is run before the database is closed when the daemon shuts down.
+		PreShutdown: func(s *state.State) error {
+			logger.Infof("This is a hook that is run on peer %q before the daemon shuts down", s.Name())
+
+			return nil
+		},
+
+		// Policies configure how each hook is run.
+		Policies: map[config.HookType]config.HookPolicy{
+			// Don't hold up the removal of a cluster member if the hook fails or hangs on a peer.
+			config.PostRemove: {Timeout: 30 * time.Second, OnFailure: config.HookFailureWarn},
+
+			// Retry a failed join hook a few times before the member is removed from the cluster again.
+			config.PostJoin: {Timeout: time.Minute, Retries: 3, RetryDelay: time.Second},
+
+			// Heartbeats shouldn't wait for the hook.
+			config.OnHeartbeat: {Async: true, Timeout: 10 * time.Second},
+		},
 	}
 
 	return m.Start(cmd.Context(), api.Endpoints, database.SchemaExtensions, api.Extensions(), exampleHooks)
@@ -192,6 +298,14 @@ func main() {
 
 	app.PersistentFlags().StringVar(&daemonCmd.flagStateDir, "state-dir", "", "Path to store state information"+"``")
 	app.PersistentFlags().StringVar(&daemonCmd.flagSocketGroup, "socket-group", "", "Group to set socket's group ownership to")
+	app.PersistentFlags().StringVar(&daemonCmd.flagLogFile, "log-file", "", "Path to a file to write logs to, in addition to stderr")
+	app.PersistentFlags().StringVar(&daemonCmd.flagLogFormat, "log-format", "text", "Format of log lines (text, json, or journald)")
+	app.PersistentFlags().StringVar(&daemonCmd.flagTraceEndpoint, "trace-endpoint", "", "URL of an OTLP/HTTP collector to send traces to")
+	app.PersistentFlags().StringVar(&daemonCmd.flagTraceFile, "trace-file", "", "Path to a file to write traces to")
+	app.PersistentFlags().StringVar(&daemonCmd.flagOIDCIssuer, "oidc-issuer", "", "Issuer of OpenID Connect tokens to accept")
+	app.PersistentFlags().StringVar(&daemonCmd.flagOIDCAudience, "oidc-audience", "", "Audience that OpenID Connect tokens must be issued for")
+	app.PersistentFlags().StringVar(&daemonCmd.flagOIDCJWKS, "oidc-jwks", "", "URL or path of the key set used to verify OpenID Connect tokens")
+	app.PersistentFlags().BoolVar(&daemonCmd.flagEmbedded, "embedded", false, "Bootstrap onto a local SQLite database until the first join token is issued")
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
@@ -42,6 +42,14 @@ token_node3=$(microctl --state-dir "${test_dir}/c1" tokens add "c3")
 microctl --state-dir "${test_dir}/c2" init "c2" 127.0.0.1:9002 --token "${token_node2}"
 microctl --state-dir "${test_dir}/c3" init "c3" 127.0.0.1:9003 --token "${token_node3}"
 
+# Ensure the cluster can be managed remotely by a trusted client
+export XDG_CONFIG_HOME="${test_dir}/client"
+token_client=$(microctl --state-dir "${test_dir}/c1" tokens add "operator")
+microctl remote add cluster "${token_client}" --client-name "operator"
+microctl --remote cluster cluster list
+microctl --remote cluster clients list | grep -q "operator"
+! microctl remote add again "${token_client}" --client-name "operator"
+
 # Clean up
 if [ -n "${CLUSTER_INSPECT:-}" ]; then
   echo "Pausing to inspect... press enter when done"
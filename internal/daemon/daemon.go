@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/canonical/lxd/lxd/db/schema"
@@ -19,6 +21,9 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/client"
@@ -27,17 +32,29 @@ import (
 	"github.com/canonical/microcluster/internal/db"
 	"github.com/canonical/microcluster/internal/endpoints"
 	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/hooks"
 	internalREST "github.com/canonical/microcluster/internal/rest"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/rest/resources"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/internal/tracing"
 	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/microcluster/clock"
+	"github.com/canonical/microcluster/microcluster/faults"
 	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
 	"github.com/canonical/microcluster/rest/types"
+	"github.com/canonical/microcluster/tasks"
 )
 
+// shutdownDrainTimeout is how long to wait for in-flight requests to complete when shutting down.
+const shutdownDrainTimeout = 30 * time.Second
+
+// shutdownTransferTimeout is how long to wait for dqlite leadership to be handed over when shutting down.
+const shutdownTransferTimeout = 10 * time.Second
+
 // Daemon holds information for the microcluster daemon.
 type Daemon struct {
 	project string // The project refers to the name of the go-project that is calling MicroCluster.
@@ -52,12 +69,14 @@ type Daemon struct {
 	clusterCert *shared.CertInfo
 
 	endpoints *endpoints.Endpoints
+	requests  *endpoints.Requests // In-flight API requests, drained on shutdown.
 	db        *db.DB
 
 	fsWatcher  *sys.Watcher
 	trustStore *trust.Store
 
-	hooks config.Hooks // Hooks to be called upon various daemon actions.
+	hooks      config.Hooks  // Hooks to be called upon various daemon actions.
+	hookRunner *hooks.Runner // Runs hooks according to their policies, and records their runs.
 
 	ReadyChan      chan struct{}      // Closed when the daemon is fully ready.
 	shutdownCtx    context.Context    // Cancelled when shutdown starts.
@@ -69,7 +88,42 @@ type Daemon struct {
 	// stop is a sync.Once which wraps the daemon's stop sequence. Each call will block until the first one completes.
 	stop func() error
 
-	extensionServers []rest.Server
+	// ReExec replaces the daemon with a fresh one after it is removed from the cluster and its state is reset.
+	// Defaults to re-executing the process.
+	ReExec func()
+
+	// Faults, if set, injects network faults into the connections between this daemon and other cluster members.
+	// For use in tests only.
+	Faults *faults.Injector
+
+	// Clock tells the time for heartbeats, timeouts, and timestamps. Defaults to the system clock.
+	Clock clock.Clock
+
+	// Embedded bootstraps the cluster onto a local SQLite database, without dqlite or the cluster network listener.
+	// The database is converted to dqlite when the first join token is issued.
+	Embedded  bool
+	convertMu sync.Mutex // Held while the embedded database is converted to dqlite.
+
+	// killed is set by Kill to skip the graceful steps of the stop sequence.
+	killed atomic.Bool
+
+	// resolvedAddress holds the IP addresses that the listen address resolved to when it was last configured.
+	resolvedAddress atomic.Pointer[types.AddrPorts]
+
+	extensionServers  []rest.Server
+	extendedEndpoints rest.Resources     // Endpoints added by external usage of MicroCluster.
+	healthChecks      []rest.HealthCheck // Health checks added by external usage of MicroCluster.
+
+	heartbeatConsistency bool                   // Whether the leader checks cluster consistency during each heartbeat.
+	pendingTimeout       time.Duration          // How long a cluster member may remain pending before it is removed.
+	authenticators       []access.Authenticator // Identify the sender of requests that are not from a cluster member.
+
+	tasks       *tasks.Scheduler   // Background tasks registered on this cluster member.
+	tasksCancel context.CancelFunc // Tells background tasks to stop, ahead of the rest of the daemon.
+
+	joinGuard        *trust.JoinGuard // Failed join attempts received by this cluster member.
+	clients          *trust.Clients   // Client certificates trusted through the clients API.
+	clusterDisableMu sync.Mutex       // Held while this cluster member is being removed.
 }
 
 // NewDaemon initializes the Daemon context and channels.
@@ -77,12 +131,65 @@ func NewDaemon(project string) *Daemon {
 	d := &Daemon{
 		shutdownDoneCh: make(chan error),
 		ReadyChan:      make(chan struct{}),
+		requests:       endpoints.NewRequests(),
 		project:        project,
+		joinGuard:      trust.NewJoinGuard(),
+		clients:        trust.NewClients(),
+		pendingTimeout: resources.DefaultPendingMemberTimeout,
+		ReExec:         reExecProcess,
+		Clock:          clock.New(),
 	}
 
 	d.stop = sync.OnceValue(func() error {
+		if !d.killed.Load() {
+			// Draining rejects any new requests, while in-flight requests keep the shutdown context to complete with.
+			drainCtx, drainCancel := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+			defer drainCancel()
+
+			err := d.requests.Drain(drainCtx)
+			if err != nil {
+				logger.Warn("Shutting down with requests still in progress", logger.Ctx{"error": err})
+			}
+
+			// Tell background tasks to stop, while those that are running can still complete their transactions.
+			if d.tasksCancel != nil {
+				d.tasksCancel()
+				d.tasks.Wait()
+			}
+		}
+
+		// Cancel anything else running on behalf of the daemon, now that requests and tasks are done.
 		d.shutdownCancel()
 
+		if !d.killed.Load() {
+			// Hand over dqlite leadership so that the remaining members don't have to wait for an election.
+			transferCtx, transferCancel := context.WithTimeout(context.Background(), shutdownTransferTimeout)
+			defer transferCancel()
+
+			err := d.db.TransferLeadership(transferCtx)
+			if err != nil {
+				logger.Warn("Shutting down without handing over dqlite leadership", logger.Ctx{"error": err})
+			}
+
+			// The shutdown context is already cancelled, so give the hook and its executables a context of their own.
+			hookCtx, hookCancel := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+			defer hookCancel()
+
+			err = d.hooks.PreShutdown(hookState(d.State(), hookCtx))
+			if err != nil {
+				logger.Error("Failed to run pre-shutdown hook", logger.Ctx{"error": err})
+			}
+
+			// Give asynchronous hooks a chance to finish before the database is closed.
+			hooksCtx, hooksCancel := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+			defer hooksCancel()
+
+			err = d.hookRunner.Wait(hooksCtx)
+			if err != nil {
+				logger.Warn("Shutting down with asynchronous hooks still running", logger.Ctx{"error": err})
+			}
+		}
+
 		err := d.db.Stop()
 		if err != nil {
 			return fmt.Errorf("Failed shutting down database: %w", err)
@@ -94,12 +201,40 @@ func NewDaemon(project string) *Daemon {
 	return d
 }
 
+// reExecProcess replaces the running process with a fresh copy of its executable.
+func reExecProcess() {
+	execPath, err := os.Readlink("/proc/self/exe")
+	if err != nil {
+		execPath = "bad-exec-path"
+	}
+
+	// The execPath from /proc/self/exe can end with " (deleted)" if the lxd binary has been removed/changed
+	// since the lxd process was started, strip this so that we only return a valid path.
+	execPath = strings.TrimSuffix(execPath, " (deleted)")
+	err = unix.Exec(execPath, os.Args, os.Environ())
+	if err != nil {
+		logger.Error("Failed restarting daemon", logger.Ctx{"err": err})
+	}
+}
+
+// Kill stops the database and all listeners as abruptly as possible, as if the process had crashed. In-flight
+// requests are not drained, dqlite leadership is not handed over, and the pre-shutdown hook is not run.
+func (d *Daemon) Kill() error {
+	d.killed.Store(true)
+
+	return d.stop()
+}
+
 // Run initializes the Daemon with the given configuration, starts the database, and blocks until the daemon is cancelled.
 // - `extensionsAPI` is a list of endpoints to be served over `/1.0`.
 // - `extensionsSchema` is a list of schema updates in the order that they should be applied.
 // - `extensionServers` is a list of rest.Server that will be initialized and managed by microcluster.
+// - `healthChecks` is a list of rest.HealthCheck that will be reported alongside the built-in health checks.
+// - `heartbeatConsistency` determines whether the leader checks cluster consistency during each heartbeat.
+// - `pendingTimeout` is how long a cluster member may remain pending before the leader removes it, if non-zero.
+// - `authenticators` identify the sender of requests that are not from a cluster member or over the unix socket.
 // - `hooks` are a set of functions that trigger at certain points during cluster communication.
-func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, socketGroup string, extensionsAPI []rest.Endpoint, extensionsSchema []schema.Update, apiExtensions []string, extensionServers []rest.Server, hooks *config.Hooks) error {
+func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, socketGroup string, extensionsAPI []rest.Endpoint, extensionsSchema []schema.Update, apiExtensions []string, extensionServers []rest.Server, healthChecks []rest.HealthCheck, heartbeatConsistency bool, pendingTimeout time.Duration, authenticators []access.Authenticator, hooks *config.Hooks) error {
 	d.shutdownCtx, d.shutdownCancel = context.WithCancel(ctx)
 	if stateDir == "" {
 		stateDir = os.Getenv(sys.StateDir)
@@ -121,12 +256,24 @@ func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, so
 	}
 
 	d.extensionServers = extensionServers
+	d.heartbeatConsistency = heartbeatConsistency
+	if pendingTimeout > 0 {
+		d.pendingTimeout = pendingTimeout
+	}
+
+	d.authenticators = authenticators
+	d.tasks = tasks.NewScheduler(d.Clock, func() bool { return d.db.IsOpen() }, func() (bool, <-chan struct{}) { return d.db.Leadership() })
 
-	err = d.init(listenPort, extensionsAPI, extensionsSchema, apiExtensions, hooks)
+	err = d.init(listenPort, extensionsAPI, extensionsSchema, apiExtensions, healthChecks, hooks)
 	if err != nil {
 		return fmt.Errorf("Daemon failed to start: %w", err)
 	}
 
+	// Start tasks before the post-start hook, so that any tasks it registers are scheduled straight away.
+	var tasksCtx context.Context
+	tasksCtx, d.tasksCancel = context.WithCancel(d.shutdownCtx)
+	d.tasks.Start(tasksCtx)
+
 	err = d.hooks.OnStart(d.State())
 	if err != nil {
 		return fmt.Errorf("Failed to run post-start hook: %w", err)
@@ -144,10 +291,14 @@ func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, so
 	}
 }
 
-func (d *Daemon) init(listenPort string, extendedEndpoints []rest.Endpoint, schemaExtensions []schema.Update, apiExtensions []string, hooks *config.Hooks) error {
+func (d *Daemon) init(listenPort string, extendedEndpoints []rest.Endpoint, schemaExtensions []schema.Update, apiExtensions []string, healthChecks []rest.HealthCheck, hooks *config.Hooks) error {
 	d.applyHooks(hooks)
 
-	var err error
+	err := d.applyHookPolicies()
+	if err != nil {
+		return err
+	}
+
 	d.name, err = os.Hostname()
 	if err != nil {
 		return fmt.Errorf("Failed to assign default system name: %w", err)
@@ -175,12 +326,14 @@ func (d *Daemon) init(listenPort string, extendedEndpoints []rest.Endpoint, sche
 		return fmt.Errorf("Failed to initialize trust store: %w", err)
 	}
 
-	d.db = db.NewDB(d.shutdownCtx, d.serverCert, d.ClusterCert, d.os)
+	d.db = db.NewDB(d.shutdownCtx, d.serverCert, d.ClusterCert, d.os, d.dial(), d.Clock)
+	d.db.OnLeaderChange(d.onLeaderChange)
 
 	// Apply extensions to API/Schema.
-	resources.ExtendedEndpoints.Endpoints = append(resources.ExtendedEndpoints.Endpoints, extendedEndpoints...)
+	d.extendedEndpoints = resources.ExtendedEndpoints(extendedEndpoints)
+	d.healthChecks = healthChecks
 
-	ctlServer := d.initServer(resources.UnixEndpoints, resources.InternalEndpoints, resources.PublicEndpoints, resources.ExtendedEndpoints)
+	ctlServer := d.initServer(resources.UnixEndpoints, resources.InternalEndpoints, resources.PublicEndpoints, d.extendedEndpoints)
 	ctl := endpoints.NewSocket(d.shutdownCtx, ctlServer, d.os.ControlSocket(), d.os.SocketGroup)
 	d.endpoints = endpoints.NewEndpoints(d.shutdownCtx, ctl)
 	err = d.endpoints.Up()
@@ -189,7 +342,7 @@ func (d *Daemon) init(listenPort string, extendedEndpoints []rest.Endpoint, sche
 	}
 
 	if listenPort != "" {
-		server := d.initServer(resources.PublicEndpoints, resources.ExtendedEndpoints)
+		server := d.initServer(resources.PublicEndpoints, d.extendedEndpoints)
 		url := api.NewURL().Host(fmt.Sprintf(":%s", listenPort))
 		network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, *url, d.serverCert)
 		err = d.endpoints.Add(network)
@@ -218,6 +371,7 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 	noOpHook := func(s *state.State) error { return nil }
 	noOpRemoveHook := func(s *state.State, force bool) error { return nil }
 	noOpInitHook := func(s *state.State, initConfig map[string]string) error { return nil }
+	noOpJoinRejectedHook := func(s *state.State, name string, source string, failures int) error { return nil }
 
 	if hooks == nil {
 		d.hooks = config.Hooks{}
@@ -249,10 +403,26 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 		d.hooks.OnHeartbeat = noOpHook
 	}
 
+	if d.hooks.OnLeaderElected == nil {
+		d.hooks.OnLeaderElected = noOpHook
+	}
+
+	if d.hooks.OnLeaderLost == nil {
+		d.hooks.OnLeaderLost = noOpHook
+	}
+
 	if d.hooks.OnNewMember == nil {
 		d.hooks.OnNewMember = noOpHook
 	}
 
+	if d.hooks.OnJoinRejected == nil {
+		d.hooks.OnJoinRejected = noOpJoinRejectedHook
+	}
+
+	if d.hooks.PreShutdown == nil {
+		d.hooks.PreShutdown = noOpHook
+	}
+
 	if d.hooks.PreRemove == nil {
 		d.hooks.PreRemove = noOpRemoveHook
 	}
@@ -260,10 +430,190 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 	if d.hooks.PostRemove == nil {
 		d.hooks.PostRemove = noOpRemoveHook
 	}
+
+	// Run the original hooks in place of any unset hooks that take a HookContext.
+	if d.hooks.PreRemoveWithContext == nil {
+		preRemove := d.hooks.PreRemove
+		d.hooks.PreRemoveWithContext = func(s *state.State, hookCtx config.HookContext) error { return preRemove(s, hookCtx.Force) }
+	}
+
+	if d.hooks.PostRemoveWithContext == nil {
+		postRemove := d.hooks.PostRemove
+		d.hooks.PostRemoveWithContext = func(s *state.State, hookCtx config.HookContext) error { return postRemove(s, hookCtx.Force) }
+	}
+
+	if d.hooks.OnHeartbeatWithContext == nil {
+		onHeartbeat := d.hooks.OnHeartbeat
+		d.hooks.OnHeartbeatWithContext = func(s *state.State, hookCtx config.HookContext) error { return onHeartbeat(s) }
+	}
+
+	if d.hooks.OnNewMemberWithContext == nil {
+		onNewMember := d.hooks.OnNewMember
+		d.hooks.OnNewMemberWithContext = func(s *state.State, hookCtx config.HookContext) error { return onNewMember(s) }
+	}
+
+	// Trace the execution of each hook.
+	d.hooks.PreBootstrap = traceInitHook("PreBootstrap", d.hooks.PreBootstrap)
+	d.hooks.PostBootstrap = traceInitHook("PostBootstrap", d.hooks.PostBootstrap)
+	d.hooks.PostJoin = traceInitHook("PostJoin", d.hooks.PostJoin)
+	d.hooks.PreJoin = traceInitHook("PreJoin", d.hooks.PreJoin)
+	d.hooks.OnStart = traceHook("OnStart", d.hooks.OnStart)
+	d.hooks.OnHeartbeatWithContext = traceContextHook("OnHeartbeat", d.hooks.OnHeartbeatWithContext)
+	d.hooks.OnLeaderElected = traceHook("OnLeaderElected", d.hooks.OnLeaderElected)
+	d.hooks.OnLeaderLost = traceHook("OnLeaderLost", d.hooks.OnLeaderLost)
+	d.hooks.OnNewMemberWithContext = traceContextHook("OnNewMember", d.hooks.OnNewMemberWithContext)
+	d.hooks.OnJoinRejected = traceJoinRejectedHook("OnJoinRejected", d.hooks.OnJoinRejected)
+	d.hooks.PreShutdown = traceHook("PreShutdown", d.hooks.PreShutdown)
+	d.hooks.PreRemoveWithContext = traceContextHook("PreRemove", d.hooks.PreRemoveWithContext)
+	d.hooks.PostRemoveWithContext = traceContextHook("PostRemove", d.hooks.PostRemoveWithContext)
+}
+
+// applyHookPolicies wraps each hook so that it runs according to its configured policy, followed by any executables in
+// the hook's directory under the state directory. Each attempt at running the hook is traced separately.
+func (d *Daemon) applyHookPolicies() error {
+	runner, err := hooks.NewRunner(d.Clock, d.hooks.Policies)
+	if err != nil {
+		return err
+	}
+
+	d.hookRunner = runner
+	d.hooks.PreBootstrap = d.policyInitHook(internalTypes.PreBootstrap, d.hooks.PreBootstrap)
+	d.hooks.PostBootstrap = d.policyInitHook(internalTypes.PostBootstrap, d.hooks.PostBootstrap)
+	d.hooks.PostJoin = d.policyInitHook(internalTypes.PostJoin, d.hooks.PostJoin)
+	d.hooks.PreJoin = d.policyInitHook(internalTypes.PreJoin, d.hooks.PreJoin)
+	d.hooks.OnStart = d.policyHook(internalTypes.OnStart, d.hooks.OnStart)
+	d.hooks.OnHeartbeatWithContext = d.policyContextHook(internalTypes.OnHeartbeat, d.hooks.OnHeartbeatWithContext)
+	d.hooks.OnLeaderElected = d.policyHook(internalTypes.OnLeaderElected, d.hooks.OnLeaderElected)
+	d.hooks.OnLeaderLost = d.policyHook(internalTypes.OnLeaderLost, d.hooks.OnLeaderLost)
+	d.hooks.OnNewMemberWithContext = d.policyContextHook(internalTypes.OnNewMember, d.hooks.OnNewMemberWithContext)
+	d.hooks.OnJoinRejected = d.policyJoinRejectedHook(internalTypes.OnJoinRejected, d.hooks.OnJoinRejected)
+	d.hooks.PreShutdown = d.policyHook(internalTypes.PreShutdown, d.hooks.PreShutdown)
+	d.hooks.PreRemoveWithContext = d.policyContextHook(internalTypes.PreRemove, d.hooks.PreRemoveWithContext)
+	d.hooks.PostRemoveWithContext = d.policyContextHook(internalTypes.PostRemove, d.hooks.PostRemoveWithContext)
+
+	return nil
+}
+
+// hookState returns a copy of the state whose context is that of a single hook attempt.
+func hookState(s *state.State, ctx context.Context) *state.State {
+	attemptState := *s
+	attemptState.Context = ctx
+
+	return &attemptState
+}
+
+// runHook runs the hook of the given type according to its policy, followed by its executables.
+func (d *Daemon) runHook(s *state.State, event internalTypes.HookEvent, member string, hook func(s *state.State) error) error {
+	event.Name = s.Name()
+	event.Address = s.Address().URL.Host
+
+	// Hooks run on behalf of a request are traced under the request's span.
+	ctx := s.Context
+	if s.RequestContext != nil {
+		ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(s.RequestContext))
+	}
+
+	return d.hookRunner.Run(ctx, event.Hook, member, func(ctx context.Context) error {
+		err := hook(hookState(s, ctx))
+		if err != nil {
+			return err
+		}
+
+		return hooks.RunExecutables(ctx, d.os.HookDir(string(event.Hook)), event)
+	})
+}
+
+// policyHook runs the hook of the given type according to its policy.
+func (d *Daemon) policyHook(hookType internalTypes.HookType, hook func(s *state.State) error) func(s *state.State) error {
+	return func(s *state.State) error {
+		return d.runHook(s, internalTypes.HookEvent{Hook: hookType}, "", hook)
+	}
+}
+
+// policyInitHook runs the bootstrap or join hook of the given type according to its policy.
+func (d *Daemon) policyInitHook(hookType internalTypes.HookType, hook func(s *state.State, initConfig map[string]string) error) func(s *state.State, initConfig map[string]string) error {
+	return func(s *state.State, initConfig map[string]string) error {
+		event := internalTypes.HookEvent{Hook: hookType, InitConfig: initConfig}
+
+		return d.runHook(s, event, s.Name(), func(s *state.State) error { return hook(s, initConfig) })
+	}
+}
+
+// policyContextHook runs the hook of the given type according to its policy, recording the member it was run for.
+func (d *Daemon) policyContextHook(hookType internalTypes.HookType, hook func(s *state.State, hookCtx config.HookContext) error) func(s *state.State, hookCtx config.HookContext) error {
+	return func(s *state.State, hookCtx config.HookContext) error {
+		event := internalTypes.HookEvent{Hook: hookType, Initiator: hookCtx.Initiator, Force: hookCtx.Force, Heartbeat: hookCtx.Heartbeat}
+		if hookCtx.Member.Name != "" {
+			event.Member = &hookCtx.Member
+		}
+
+		return d.runHook(s, event, hookCtx.Member.Name, func(s *state.State) error { return hook(s, hookCtx) })
+	}
+}
+
+// policyJoinRejectedHook runs the rejected join hook according to its policy, recording the rejected member name.
+func (d *Daemon) policyJoinRejectedHook(hookType internalTypes.HookType, hook func(s *state.State, joinName string, source string, failures int) error) func(s *state.State, joinName string, source string, failures int) error {
+	return func(s *state.State, joinName string, source string, failures int) error {
+		event := internalTypes.HookEvent{Hook: hookType, Member: &internalTypes.ClusterMemberLocal{Name: joinName}, Source: source, Failures: failures}
+
+		return d.runHook(s, event, joinName, func(s *state.State) error { return hook(s, joinName, source, failures) })
+	}
+}
+
+// traceHook wraps the hook with the given name in a span.
+func traceHook(name string, hook func(s *state.State) error) func(s *state.State) error {
+	return func(s *state.State) error {
+		ctx, span := tracing.Start(s.Context, "hook "+name)
+		err := hook(hookState(s, ctx))
+		tracing.End(span, err)
+
+		return err
+	}
+}
+
+// traceInitHook wraps the bootstrap or join hook with the given name in a span.
+func traceInitHook(name string, hook func(s *state.State, initConfig map[string]string) error) func(s *state.State, initConfig map[string]string) error {
+	return func(s *state.State, initConfig map[string]string) error {
+		ctx, span := tracing.Start(s.Context, "hook "+name)
+		err := hook(hookState(s, ctx), initConfig)
+		tracing.End(span, err)
+
+		return err
+	}
+}
+
+// traceContextHook wraps the hook with the given name in a span, recording the member and initiator it was run for.
+func traceContextHook(name string, hook func(s *state.State, hookCtx config.HookContext) error) func(s *state.State, hookCtx config.HookContext) error {
+	return func(s *state.State, hookCtx config.HookContext) error {
+		ctx, span := tracing.Start(s.Context, "hook "+name, trace.WithAttributes(
+			attribute.String("member", hookCtx.Member.Name),
+			attribute.String("initiator", hookCtx.Initiator),
+			attribute.Bool("force", hookCtx.Force),
+		))
+		err := hook(hookState(s, ctx), hookCtx)
+		tracing.End(span, err)
+
+		return err
+	}
+}
+
+// traceJoinRejectedHook wraps the rejected join hook with the given name in a span.
+func traceJoinRejectedHook(name string, hook func(s *state.State, joinName string, source string, failures int) error) func(s *state.State, joinName string, source string, failures int) error {
+	return func(s *state.State, joinName string, source string, failures int) error {
+		ctx, span := tracing.Start(s.Context, "hook "+name, trace.WithAttributes(attribute.String("name", joinName), attribute.String("source", source), attribute.Int("failures", failures)))
+		err := hook(hookState(s, ctx), joinName, source, failures)
+		tracing.End(span, err)
+
+		return err
+	}
 }
 
 func (d *Daemon) reloadIfBootstrapped() error {
 	_, err := os.Stat(filepath.Join(d.os.DatabaseDir, "info.yaml"))
+	if os.IsNotExist(err) {
+		_, err = os.Stat(d.os.EmbeddedDatabasePath())
+	}
+
 	if err != nil {
 		if os.IsNotExist(err) {
 			logger.Warn("microcluster database is uninitialized")
@@ -338,14 +688,14 @@ func (d *Daemon) initServer(resources ...rest.Resources) *http.Server {
 	state := d.State()
 	for _, endpoints := range resources {
 		for _, e := range endpoints.Endpoints {
-			internalREST.HandleEndpoint(state, mux, string(endpoints.Path), e)
+			internalREST.HandleEndpoint(state, mux, string(endpoints.Path), e, d.authenticators)
 
 			for _, alias := range e.Aliases {
 				ae := e
 				ae.Name = alias.Name
 				ae.Path = alias.Path
 
-				internalREST.HandleEndpoint(state, mux, string(endpoints.Path), ae)
+				internalREST.HandleEndpoint(state, mux, string(endpoints.Path), ae, d.authenticators)
 			}
 		}
 	}
@@ -382,13 +732,13 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return fmt.Errorf("Failed to parse server certificate when bootstrapping API: %w", err)
 	}
 
-	addrPort, err := types.ParseAddrPort(d.address.URL.Host)
+	hostPort, err := types.ParseHostPort(d.address.URL.Host)
 	if err != nil {
 		return fmt.Errorf("Failed to parse listen address when bootstrapping API: %w", err)
 	}
 
 	localNode := trust.Remote{
-		Location:    trust.Location{Name: d.name, Address: addrPort},
+		Location:    trust.Location{Name: d.name, Address: hostPort},
 		Certificate: types.X509Certificate{Certificate: serverCert},
 	}
 
@@ -404,16 +754,13 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return err
 	}
 
-	server := d.initServer(resources.InternalEndpoints, resources.PublicEndpoints, resources.ExtendedEndpoints)
-	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, d.address, d.ClusterCert())
-	err = d.endpoints.Down(endpoints.EndpointNetwork)
-	if err != nil {
-		return err
-	}
-
-	err = d.endpoints.Add(network)
-	if err != nil {
-		return err
+	// An embedded database is only used to bootstrap, or if it was bootstrapped that way and not yet converted.
+	embedded := (bootstrap && d.Embedded) || (len(joinAddresses) == 0 && shared.PathExists(d.os.EmbeddedDatabasePath()))
+	if !embedded {
+		err = d.startNetwork()
+		if err != nil {
+			return err
+		}
 	}
 
 	// If bootstrapping the first node, just open the database and create an entry for ourselves.
@@ -424,11 +771,17 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 			Certificate: localNode.Certificate.String(),
 			Heartbeat:   time.Time{},
 			Role:        cluster.Pending,
+			CreatedAt:   d.Clock.Now(),
 		}
 
 		clusterMember.SchemaInternal, clusterMember.SchemaExternal = d.db.Schema().Version()
 
-		err = d.db.Bootstrap(d.Extensions, d.project, d.address, clusterMember)
+		if embedded {
+			err = d.db.BootstrapEmbedded(d.Extensions, d.project, d.address, clusterMember)
+		} else {
+			err = d.db.Bootstrap(d.Extensions, d.project, d.address, clusterMember)
+		}
+
 		if err != nil {
 			return err
 		}
@@ -453,6 +806,21 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return nil
 	}
 
+	if embedded {
+		err = d.db.StartEmbedded(d.Extensions, d.project, d.address)
+		if err != nil {
+			return fmt.Errorf("Failed to open embedded database: %w", err)
+		}
+
+		err = d.trustStore.Refresh()
+		if err != nil {
+			return err
+		}
+
+		// There are no other cluster members to notify.
+		return d.addExtensionServers()
+	}
+
 	if len(joinAddresses) != 0 {
 		err = d.db.Join(d.Extensions, d.project, d.address, joinAddresses...)
 		if err != nil {
@@ -476,7 +844,7 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return err
 	}
 
-	cluster, err := d.trustStore.Remotes().Cluster(false, d.ServerCert(), publicKey)
+	cluster, err := d.trustStore.Remotes().Cluster(false, d.ServerCert(), publicKey, d.dial())
 	if err != nil {
 		return err
 	}
@@ -538,18 +906,18 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 
 		// If this was a join request, instruct all peers to run their OnNewMember hook.
 		if len(joinAddresses) > 0 {
-			addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+			hostPort, err := types.ParseHostPort(c.URL().URL.Host)
 			if err != nil {
 				return err
 			}
 
-			remote := remotes.RemoteByAddress(addrPort)
+			remote := remotes.RemoteByAddress(hostPort)
 			if remote == nil {
 				return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
 			}
 
 			// Run the OnNewMember hook, and skip errors on any nodes that are still in the process of joining.
-			err = internalClient.RunNewMemberHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookNewMemberOptions{Name: localMemberInfo.Name})
+			err = internalClient.RunNewMemberHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookNewMemberOptions{Name: localMemberInfo.Name, Member: &localMemberInfo})
 			if err != nil && err.Error() != "Daemon not yet initialized" {
 				return err
 			}
@@ -574,6 +942,41 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 	return nil
 }
 
+// startNetwork (re)starts the listener for the cluster network.
+func (d *Daemon) startNetwork() error {
+	server := d.initServer(resources.InternalEndpoints, resources.PublicEndpoints, d.extendedEndpoints)
+	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, d.address, d.ClusterCert())
+	if d.Faults != nil {
+		address := d.address.URL.Host
+		network.WrapListener(func(l net.Listener) net.Listener { return d.Faults.Listen(address, l) })
+	}
+
+	err := d.endpoints.Down(endpoints.EndpointNetwork)
+	if err != nil {
+		return err
+	}
+
+	return d.endpoints.Add(network)
+}
+
+// ConvertEmbedded moves the embedded database over to dqlite and starts the cluster network listener, so that other
+// members can join. It does nothing if the database is not embedded.
+func (d *Daemon) ConvertEmbedded() error {
+	d.convertMu.Lock()
+	defer d.convertMu.Unlock()
+
+	if !d.db.IsEmbedded() {
+		return nil
+	}
+
+	err := d.db.ConvertEmbedded(d.project)
+	if err != nil {
+		return fmt.Errorf("Failed to convert embedded database: %w", err)
+	}
+
+	return d.startNetwork()
+}
+
 // addExtensionServers initialises a new *endpoints.Network for each extension server and adds it to the Daemon endpoints.
 func (d *Daemon) addExtensionServers() error {
 	var networks []endpoints.Endpoint
@@ -648,6 +1051,30 @@ func (d *Daemon) ReloadClusterCert() error {
 	return nil
 }
 
+// dial returns the function used to connect to other cluster members, or nil to connect directly.
+func (d *Daemon) dial() internalClient.DialFunc {
+	if d.Faults == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return d.Faults.Dial(ctx, d.Address().URL.Host, network, address)
+	}
+}
+
+// onLeaderChange runs the OnLeaderElected or OnLeaderLost hook when this member gains or loses dqlite leadership.
+func (d *Daemon) onLeaderChange(leader bool) {
+	hook, name := d.hooks.OnLeaderLost, "OnLeaderLost"
+	if leader {
+		hook, name = d.hooks.OnLeaderElected, "OnLeaderElected"
+	}
+
+	err := hook(d.State())
+	if err != nil {
+		logger.Error("Failed to run leadership hook", logger.Ctx{"hook": name, "error": err})
+	}
+}
+
 // ServerCert ensures both the daemon and state have the same server cert.
 func (d *Daemon) ServerCert() *shared.CertInfo {
 	return d.serverCert
@@ -666,32 +1093,27 @@ func (d *Daemon) Name() string {
 
 // State creates a State instance with the daemon's stateful components.
 func (d *Daemon) State() *state.State {
-	state.PreRemoveHook = d.hooks.PreRemove
-	state.PostRemoveHook = d.hooks.PostRemove
-	state.OnHeartbeatHook = d.hooks.OnHeartbeat
-	state.OnNewMemberHook = d.hooks.OnNewMember
-	state.ReloadClusterCert = d.ReloadClusterCert
-	state.StopListeners = func() error {
-		err := d.fsWatcher.Close()
-		if err != nil {
-			return err
-		}
-
-		return d.endpoints.Down()
-	}
-
 	state := &state.State{
-		Context:     d.shutdownCtx,
-		ReadyCh:     d.ReadyChan,
-		OS:          d.os,
-		Address:     d.Address,
-		Name:        d.Name,
-		Endpoints:   d.endpoints,
-		ServerCert:  d.ServerCert,
-		ClusterCert: d.ClusterCert,
-		Database:    d.db,
-		Remotes:     d.trustStore.Remotes,
-		StartAPI:    d.StartAPI,
+		Context:         d.shutdownCtx,
+		ReadyCh:         d.ReadyChan,
+		OS:              d.os,
+		Address:         d.Address,
+		ResolvedAddress: d.ResolvedAddress,
+		Name:            d.Name,
+		Endpoints:       d.endpoints,
+		Requests:        d.requests,
+		ServerCert:      d.ServerCert,
+		ClusterCert:     d.ClusterCert,
+		Database:        d.db,
+		Remotes:         d.trustStore.Remotes,
+		Tasks:           func() *tasks.Scheduler { return d.tasks },
+		HookRuns:        func() []internalTypes.HookRun { return d.hookRunner.Runs() },
+		Dial:            d.dial(),
+		Clock:           d.Clock,
+		StartAPI:        d.StartAPI,
+		ConvertEmbedded: d.ConvertEmbedded,
+		IsLeader:        d.db.IsLeader,
+		Leadership:      d.db.Leadership,
 		Stop: func() (exit func(), stopErr error) {
 			stopErr = d.stop()
 			exit = func() {
@@ -701,6 +1123,28 @@ func (d *Daemon) State() *state.State {
 			return exit, stopErr
 		},
 		Extensions: d.Extensions,
+		StopListeners: func() error {
+			err := d.fsWatcher.Close()
+			if err != nil {
+				return err
+			}
+
+			return d.endpoints.Down()
+		},
+		ReExec:             d.ReExec,
+		ReloadClusterCert:  d.ReloadClusterCert,
+		PreRemoveHook:      d.hooks.PreRemoveWithContext,
+		PostRemoveHook:     d.hooks.PostRemoveWithContext,
+		OnHeartbeatHook:    d.hooks.OnHeartbeatWithContext,
+		OnNewMemberHook:    d.hooks.OnNewMemberWithContext,
+		OnJoinRejectedHook: d.hooks.OnJoinRejected,
+
+		HealthChecks:                d.healthChecks,
+		CheckConsistencyOnHeartbeat: d.heartbeatConsistency,
+		PendingMemberTimeout:        d.pendingTimeout,
+		JoinGuard:                   d.joinGuard,
+		Clients:                     d.clients,
+		ClusterDisableMu:            &d.clusterDisableMu,
 	}
 
 	return state
@@ -734,6 +1178,32 @@ func (d *Daemon) setDaemonConfig(config *trust.Location) error {
 
 	d.address = *api.NewURL().Scheme("https").Host(config.Address.String())
 	d.name = config.Name
+	d.resolveAddress(config.Address)
 
 	return nil
 }
+
+// resolveAddress caches the IP addresses that the listen address resolves to, so that requests addressed to any of
+// them can be accepted without resolving anything per request. If the address can't be resolved, only requests
+// addressed to it by name are accepted.
+func (d *Daemon) resolveAddress(address types.HostPort) {
+	ctx, cancel := context.WithTimeout(d.shutdownCtx, 10*time.Second)
+	defer cancel()
+
+	resolved, err := address.Resolve(ctx)
+	if err != nil {
+		logger.Warn("Failed to resolve listen address", logger.Ctx{"address": address.String(), "error": err})
+	}
+
+	d.resolvedAddress.Store(&resolved)
+}
+
+// ResolvedAddress returns the IP addresses that the listen address resolved to when it was last configured.
+func (d *Daemon) ResolvedAddress() types.AddrPorts {
+	resolved := d.resolvedAddress.Load()
+	if resolved == nil {
+		return nil
+	}
+
+	return *resolved
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -17,6 +18,7 @@ import (
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/internal/extensions"
 	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/internal/tracing"
 )
 
 // Open opens the dqlite database and loads the schema.
@@ -40,7 +42,7 @@ func (db *DB) Open(ext extensions.Extensions, bootstrap bool, project string) er
 		return err
 	}
 
-	err = cluster.PrepareStmts(db.db, project, false)
+	db.stmts, err = cluster.PrepareStmts(db.db, project, false)
 	if err != nil {
 		return err
 	}
@@ -192,7 +194,7 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 		logger.Warn("Waiting for other cluster members to upgrade their versions", logger.Ctx{"address": db.listenAddr.String()})
 		select {
 		case <-db.upgradeCh:
-		case <-time.After(30 * time.Second):
+		case <-db.clock.After(30 * time.Second):
 		}
 	}
 
@@ -200,21 +202,72 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 }
 
 // Transaction handles performing a transaction on the dqlite database.
-func (db *DB) Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
+func (db *DB) Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) (err error) {
+	outerCtx, span := tracing.Start(outerCtx, "db.Transaction")
+	defer func() { tracing.End(span, err) }()
+
 	return db.retry(outerCtx, func(ctx context.Context) error {
-		err := query.Transaction(ctx, db.db, f)
+		db.handleMu.RLock()
+		defer db.handleMu.RUnlock()
+
+		err := cluster.Transaction(ctx, db.db, db.stmts, f)
 		if errors.Is(err, context.DeadlineExceeded) {
 			// If the query timed out it likely means that the leader has abruptly become unreachable.
 			// Now that this query has been cancelled, a leader election should have taken place by now.
 			// So let's retry the transaction once more in case the global database is now available again.
 			logger.Warn("Transaction timed out. Retrying once", logger.Ctx{"err": err})
-			return query.Transaction(ctx, db.db, f)
+			return cluster.Transaction(ctx, db.db, db.stmts, f)
 		}
 
 		return err
 	})
 }
 
+// ReadOnlyTransaction runs the function in a transaction on a connection with the query_only pragma set, so that the
+// database refuses any statement that would write to it. The transaction is always rolled back.
+func (db *DB) ReadOnlyTransaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) (err error) {
+	outerCtx, span := tracing.Start(outerCtx, "db.ReadOnlyTransaction")
+	defer func() { tracing.End(span, err) }()
+
+	return db.retry(outerCtx, func(ctx context.Context) error {
+		db.handleMu.RLock()
+		defer db.handleMu.RUnlock()
+
+		conn, err := db.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to get a database connection: %w", err)
+		}
+
+		defer func() { _ = conn.Close() }()
+
+		// The transaction is begun first, as it may take a write lock straight away.
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to begin transaction: %w", err)
+		}
+
+		defer func() {
+			_ = tx.Rollback()
+
+			resetCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_, err := conn.ExecContext(resetCtx, "PRAGMA query_only = 0")
+			if err != nil {
+				// Don't return the connection to the pool while it may still refuse writes.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+
+		_, err = tx.ExecContext(ctx, "PRAGMA query_only = 1")
+		if err != nil {
+			return fmt.Errorf("Failed to make database connection read-only: %w", err)
+		}
+
+		return db.stmts.Use(tx, func() error { return f(ctx, tx) })
+	})
+}
+
 func (db *DB) retry(ctx context.Context, f func(context.Context) error) error {
 	if db.ctx.Err() != nil {
 		return f(ctx)
@@ -238,7 +291,7 @@ func (db *DB) Update() error {
 	// Wait a random amount of seconds (up to 30) to space out the update.
 	wait := time.Duration(rand.Intn(30)) * time.Second
 	logger.Info("Triggering cluster auto-update soon", logger.Ctx{"wait": wait, "updateExecutable": updateExec})
-	time.Sleep(wait)
+	db.clock.Sleep(wait)
 
 	logger.Info("Triggering cluster auto-update now")
 	_, err := shared.RunCommand(updateExec)
@@ -16,6 +16,7 @@ import (
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/internal/db/update"
 	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/microcluster/clock"
 )
 
 type dbSuite struct {
@@ -437,6 +438,68 @@ func (s *dbSuite) Test_waitUpgradeAPI() {
 	}
 }
 
+// Ensures waitUpgrade stops waiting for an upgrade notification after 30 seconds.
+func (s *dbSuite) Test_waitUpgradeTimeout() {
+	db, err := NewTestDB([]schema.Update{})
+	s.NoError(err)
+
+	fakeClock := clock.NewFake(time.Now())
+	db.clock = fakeClock
+
+	localExtensions := extensions.Extensions{"internal:a", "ext", "ext2"}
+	ctx := context.Background()
+	tx, err := db.db.BeginTx(ctx, nil)
+	s.NoError(err)
+
+	for i, memberExtensions := range []extensions.Extensions{localExtensions, {"internal:a", "ext"}} {
+		_, err = cluster.CreateInternalClusterMember(ctx, tx, cluster.InternalClusterMember{
+			Name:           fmt.Sprintf("cluster-member-%d", i),
+			Address:        fmt.Sprintf("10.0.0.%d:8443", i),
+			Certificate:    fmt.Sprintf("test-cert-%d", i),
+			SchemaInternal: 1,
+			SchemaExternal: 1,
+			APIExtensions:  memberExtensions,
+			Heartbeat:      time.Time{},
+			Role:           "voter",
+		})
+
+		s.NoError(err)
+	}
+
+	s.NoError(tx.Commit())
+
+	// Use a no-op schema manager at the same schema version as the other member.
+	_, err = db.db.Exec("delete from schemas")
+	s.NoError(err)
+
+	manager := &update.SchemaUpdateManager{}
+	for _, schemaType := range []int{0, 1} {
+		_, err = db.db.Exec(`INSERT INTO schemas (version, type, updated_at) VALUES (0, ?, strftime("%s"))`, schemaType)
+		s.NoError(err)
+	}
+
+	manager.SetInternalUpdates([]schema.Update{func(ctx context.Context, tx *sql.Tx) error { return nil }})
+	manager.SetExternalUpdates([]schema.Update{func(ctx context.Context, tx *sql.Tx) error { return nil }})
+	db.schema = manager.Schema()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- db.waitUpgrade(false, localExtensions)
+	}()
+
+	// The other member is behind, so nothing happens until the full wait has elapsed.
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(29 * time.Second)
+	select {
+	case err := <-done:
+		s.Failf("waitUpgrade returned early", "error: %v", err)
+	default:
+	}
+
+	fakeClock.Advance(time.Second)
+	s.Equal(schema.ErrGracefulAbort, <-done)
+}
+
 func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 	type versionsWithExtensions struct {
 		schemaInt uint64
@@ -650,7 +713,7 @@ func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 // NewTedb returns a sqlite DB set up with the default microcluster schema.
 func NewTestDB(extensionsExternal []schema.Update) (*DB, error) {
 	var err error
-	db := &DB{ctx: context.Background(), listenAddr: *api.NewURL().Host("10.0.0.0:8443"), upgradeCh: make(chan struct{}, 1)}
+	db := &DB{ctx: context.Background(), listenAddr: *api.NewURL().Host("10.0.0.0:8443"), upgradeCh: make(chan struct{}, 1), clock: clock.NewFake(time.Now())}
 	db.db, err = sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		return nil, err
@@ -662,7 +725,7 @@ func NewTestDB(extensionsExternal []schema.Update) (*DB, error) {
 		return nil, err
 	}
 
-	err = cluster.PrepareStmts(db.db, cluster.GetCallerProject(), false)
+	db.stmts, err = cluster.PrepareStmts(db.db, cluster.GetCallerProject(), false)
 	if err != nil {
 		return nil, err
 	}
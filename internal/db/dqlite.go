@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dqlite "github.com/canonical/go-dqlite/app"
@@ -24,7 +25,6 @@ import (
 	"github.com/canonical/lxd/shared/cancel"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
-	"github.com/canonical/lxd/shared/tcp"
 
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/internal/db/update"
@@ -32,6 +32,7 @@ import (
 	"github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/microcluster/clock"
 	"github.com/canonical/microcluster/rest/types"
 )
 
@@ -44,28 +45,48 @@ type DB struct {
 	dbName string // This is db.bin.
 	os     *sys.OS
 
+	handleMu  sync.RWMutex // Held for reading while db and stmts are in use, and for writing while they are replaced.
 	db        *sql.DB
+	stmts     *cluster.Stmts // Registered statements prepared against db.
 	dqlite    *dqlite.App
+	embedded  atomic.Bool // Whether db is a local SQLite database rather than dqlite.
 	acceptCh  chan net.Conn
 	upgradeCh chan struct{}
 
+	stopCh   chan struct{} // Closed when the database begins to stop, so no more connections are handed to dqlite.
+	stopOnce sync.Once
+
 	openCanceller *cancel.Canceller
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	heartbeatLock sync.Mutex
+	role          atomic.Value // Role of this member in the dqlite cluster as of the last heartbeat or leadership check.
+
+	leaderMu       sync.Mutex
+	leader         bool          // Whether this member was the dqlite leader as of the last leadership check.
+	leaderCh       chan struct{} // Closed and replaced whenever leader changes.
+	onLeaderChange func(bool)    // Run whenever leader changes.
 
 	schema *update.SchemaUpdate
+
+	dial  client.DialFunc // Establishes connections to other cluster members, if set.
+	clock clock.Clock     // Tells the time for heartbeats and upgrade waits.
 }
 
 // Accept sends the outbound connection through the acceptCh channel to be received by dqlite.
 func (db *DB) Accept(conn net.Conn) {
-	db.acceptCh <- conn
+	select {
+	case db.acceptCh <- conn:
+	case <-db.stopCh:
+		_ = conn.Close()
+	}
 }
 
 // NewDB creates an empty db struct with no dqlite connection.
-func NewDB(ctx context.Context, serverCert *shared.CertInfo, clusterCert func() *shared.CertInfo, os *sys.OS) *DB {
+// Connections to other cluster members are established with the given dial function, if it is set.
+func NewDB(ctx context.Context, serverCert *shared.CertInfo, clusterCert func() *shared.CertInfo, os *sys.OS, dial client.DialFunc, clock clock.Clock) *DB {
 	shutdownCtx, shutdownCancel := context.WithCancel(ctx)
 
 	return &DB{
@@ -75,9 +96,13 @@ func NewDB(ctx context.Context, serverCert *shared.CertInfo, clusterCert func()
 		os:            os,
 		acceptCh:      make(chan net.Conn),
 		upgradeCh:     make(chan struct{}),
+		stopCh:        make(chan struct{}),
+		leaderCh:      make(chan struct{}),
 		ctx:           shutdownCtx,
 		cancel:        shutdownCancel,
 		openCanceller: cancel.New(context.Background()),
+		dial:          dial,
+		clock:         clock,
 	}
 }
 
@@ -122,6 +147,7 @@ func (db *DB) Bootstrap(extensions extensions.Extensions, project string, addr a
 	}
 
 	go db.loopHeartbeat()
+	go db.loopLeader(db.CheckLeader)
 
 	return nil
 }
@@ -160,12 +186,13 @@ func (db *DB) Join(extensions extensions.Extensions, project string, addr api.UR
 	}
 
 	go db.loopHeartbeat()
+	go db.loopLeader(db.CheckLeader)
 
 	return nil
 }
 
 // StartWithCluster starts up dqlite and joins the cluster.
-func (db *DB) StartWithCluster(extensions extensions.Extensions, project string, addr api.URL, clusterMembers map[string]types.AddrPort) error {
+func (db *DB) StartWithCluster(extensions extensions.Extensions, project string, addr api.URL, clusterMembers map[string]types.HostPort) error {
 	allClusterAddrs := []string{}
 	for _, clusterMemberAddrs := range clusterMembers {
 		allClusterAddrs = append(allClusterAddrs, clusterMemberAddrs.String())
@@ -176,9 +203,73 @@ func (db *DB) StartWithCluster(extensions extensions.Extensions, project string,
 
 // Leader returns a client connected to the leader of the dqlite cluster.
 func (db *DB) Leader(ctx context.Context) (*dqliteClient.Client, error) {
+	if db.IsEmbedded() {
+		return nil, ErrEmbedded
+	}
+
 	return db.dqlite.Leader(ctx)
 }
 
+// TransferLeadership hands dqlite leadership over to another reachable voter if this member is currently the leader.
+func (db *DB) TransferLeadership(ctx context.Context) error {
+	if !db.IsOpen() || db.IsEmbedded() {
+		return nil
+	}
+
+	leaderClient, err := db.dqlite.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get dqlite leader: %w", err)
+	}
+
+	defer leaderClient.Close()
+
+	leader, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get dqlite leader information: %w", err)
+	}
+
+	if leader.ID != db.dqlite.ID() {
+		return nil
+	}
+
+	members, err := db.Cluster(ctx, leaderClient)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, member := range members {
+		if member.ID == leader.ID || member.Role != dqliteClient.Voter {
+			continue
+		}
+
+		// Only hand over to members that we can currently reach.
+		memberClient, err := dqliteClient.New(ctx, member.Address, dqliteClient.WithDialFunc(db.dialFunc()))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		_ = memberClient.Close()
+
+		err = leaderClient.Transfer(ctx, member.ID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		logger.Info("Transferred dqlite leadership", logger.Ctx{"address": db.listenAddr.String(), "leader": member.Address})
+
+		return nil
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("Failed to transfer dqlite leadership: %w", lastErr)
+	}
+
+	return nil
+}
+
 // Cluster returns information about dqlite cluster members.
 func (db *DB) Cluster(ctx context.Context, client *dqliteClient.Client) ([]dqliteClient.NodeInfo, error) {
 	members, err := client.Cluster(ctx)
@@ -198,6 +289,18 @@ func (db *DB) IsOpen() bool {
 	return db.openCanceller.Err() != nil
 }
 
+// Role returns "leader" or "follower" depending on whether this member was the dqlite leader as of the last heartbeat
+// attempt or leadership check, or an empty string if that is not yet known.
+func (db *DB) Role() string {
+	if db == nil {
+		return ""
+	}
+
+	role, _ := db.role.Load().(string)
+
+	return role
+}
+
 // NotifyUpgraded sends a notification that we can stop waiting for a cluster member to be upgraded.
 func (db *DB) NotifyUpgraded() {
 	select {
@@ -218,11 +321,16 @@ func (db *DB) dialFunc() dqliteClient.DialFunc {
 	}
 }
 
-// loopHeartbeat runs the heartbeat command continuously every second.
+// loopHeartbeat runs the heartbeat command every 10 seconds until the database is stopped.
 func (db *DB) loopHeartbeat() {
 	for {
 		db.heartbeat(db.ctx)
-		time.Sleep(10 * time.Second)
+
+		select {
+		case <-db.ctx.Done():
+			return
+		case <-db.clock.After(10 * time.Second):
+		}
 	}
 }
 
@@ -248,6 +356,12 @@ func (db *DB) heartbeat(ctx context.Context) {
 		logger.Error("Failed to initiate heartbeat round", logger.Ctx{"address": db.dqlite.Address(), "error": err})
 		return
 	}
+
+	if err != nil {
+		db.role.Store("follower")
+	} else {
+		db.role.Store("leader")
+	}
 }
 
 // dqliteNetworkDial creates a connection to the internal database endpoint.
@@ -285,10 +399,26 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, erro
 	revert := revert.New()
 	defer revert.Fail()
 
-	deadline, _ := ctx.Deadline()
-	dialer := &net.Dialer{Timeout: time.Until(deadline)}
-	conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	hostPort, err := types.ParseHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid dqlite address %q: %w", addr, err)
+	}
+
+	// Resolve the host on each dial, so that members addressed by DNS name follow any change of IP.
+	addrPorts, err := hostPort.Resolve(ctx)
 	if err != nil {
+		return nil, err
+	}
+
+	var conn *tls.Conn
+	for _, addrPort := range addrPorts {
+		conn, err = client.DialTLS(ctx, db.dial, "tcp", addrPort.String(), config)
+		if err == nil {
+			break
+		}
+	}
+
+	if conn == nil {
 		return nil, fmt.Errorf("Failed connecting to HTTP endpoint %q: %w", addr, err)
 	}
 
@@ -301,17 +431,6 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, erro
 	logCtx := logger.AddContext(logger.Ctx{"local": conn.LocalAddr().String(), "remote": conn.RemoteAddr().String()})
 	logCtx.Debug("Dqlite connected outbound")
 
-	// Set outbound timeouts.
-	remoteTCP, err := tcp.ExtractConn(conn)
-	if err != nil {
-		logCtx.Error("Failed extracting TCP connection from remote connection", logger.Ctx{"error": err})
-	} else {
-		err := tcp.SetTimeouts(remoteTCP, 0)
-		if err != nil {
-			logCtx.Error("Failed setting TCP timeouts on remote connection", logger.Ctx{"error": err})
-		}
-	}
-
 	err = request.Write(conn)
 	if err != nil {
 		return nil, fmt.Errorf("Failed sending HTTP requrest to %q: %w", request.URL, err)
@@ -350,11 +469,17 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, erro
 // Stop closes the database and dqlite connection.
 func (db *DB) Stop() error {
 	db.cancel()
+	db.stopOnce.Do(func() { close(db.stopCh) })
 
 	if db.IsOpen() {
+		db.handleMu.Lock()
+
 		// The database might refuse to close if many nodes are stopping at the same time,
 		// because the dqlite connection will have been lost.
+		db.stmts.Close()
 		_ = db.db.Close()
+
+		db.handleMu.Unlock()
 	}
 
 	if db.dqlite != nil {
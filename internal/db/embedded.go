@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	dqlite "github.com/canonical/go-dqlite/app"
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+	_ "github.com/mattn/go-sqlite3" // Registers the sqlite3 driver for the embedded database.
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/sys"
+)
+
+// ErrEmbedded is returned for dqlite cluster operations on an embedded database.
+var ErrEmbedded = fmt.Errorf("Not available while running with an embedded database")
+
+// IsEmbedded returns true if the database is a local SQLite database for a single cluster member, rather than dqlite.
+func (db *DB) IsEmbedded() bool {
+	if db == nil {
+		return false
+	}
+
+	return db.embedded.Load()
+}
+
+// BootstrapEmbedded creates a local SQLite database in place of dqlite, for a cluster with a single member.
+func (db *DB) BootstrapEmbedded(extensions extensions.Extensions, project string, addr api.URL, clusterRecord cluster.InternalClusterMember) error {
+	db.listenAddr = addr
+	err := db.openEmbedded(extensions, true, project)
+	if err != nil {
+		return err
+	}
+
+	// There is no heartbeat to assign a role, so the only member is always a voter.
+	clusterRecord.APIExtensions = extensions
+	clusterRecord.Role = cluster.Role(dqliteClient.Voter.String())
+
+	return db.Transaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateInternalClusterMember(ctx, tx, clusterRecord)
+
+		return err
+	})
+}
+
+// StartEmbedded opens the existing local SQLite database, applying any schema updates.
+func (db *DB) StartEmbedded(extensions extensions.Extensions, project string, addr api.URL) error {
+	db.listenAddr = addr
+
+	return db.openEmbedded(extensions, false, project)
+}
+
+// openEmbedded opens the local SQLite database and loads the schema.
+func (db *DB) openEmbedded(ext extensions.Extensions, bootstrap bool, project string) error {
+	var err error
+	db.db, err = sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate", db.os.EmbeddedDatabasePath()))
+	if err != nil {
+		return fmt.Errorf("Failed to open embedded database: %w", err)
+	}
+
+	// SQLite only supports a single writer.
+	db.db.SetMaxOpenConns(1)
+	db.embedded.Store(true)
+
+	err = db.waitUpgrade(bootstrap, ext)
+	if err != nil {
+		return err
+	}
+
+	db.stmts, err = cluster.PrepareStmts(db.db, project, false)
+	if err != nil {
+		return err
+	}
+
+	db.openCanceller.Cancel()
+
+	// The loop carries on checking dqlite leadership if the database is later converted.
+	go db.loopLeader(db.CheckLeader)
+
+	return nil
+}
+
+// ConvertEmbedded bootstraps dqlite with the contents of the embedded database, so that other members can join.
+// The embedded database is removed once dqlite has taken over.
+func (db *DB) ConvertEmbedded(project string) error {
+	if !db.IsEmbedded() {
+		return nil
+	}
+
+	err := db.convertEmbedded(project, db.bootstrapDqlite)
+	if err != nil {
+		return err
+	}
+
+	go db.loopHeartbeat()
+
+	return nil
+}
+
+// bootstrapDqlite starts dqlite as the only member of a new cluster and opens its database. The reverter undoes this,
+// so that the daemon restarts with the embedded database if the conversion fails.
+func (db *DB) bootstrapDqlite(ctx context.Context, reverter *revert.Reverter) (*dqlite.App, *sql.DB, error) {
+	app, err := dqlite.New(db.os.DatabaseDir,
+		dqlite.WithAddress(db.listenAddr.URL.Host),
+		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to bootstrap dqlite: %w", err)
+	}
+
+	reverter.Add(func() {
+		_ = app.Close()
+
+		// Clear out dqlite's data so that the daemon restarts with the embedded database.
+		_ = os.RemoveAll(db.os.DatabaseDir)
+		_ = os.Mkdir(db.os.DatabaseDir, 0700)
+	})
+
+	err = app.Ready(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sqlDB, err := app.Open(db.ctx, db.dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return app, sqlDB, nil
+}
+
+// convertEmbedded copies the embedded database to the one opened by bootstrap, and replaces it. No transactions run
+// from the start of the copy until the replacement is in place, so that nothing written meanwhile is lost.
+func (db *DB) convertEmbedded(project string, bootstrap func(ctx context.Context, reverter *revert.Reverter) (*dqlite.App, *sql.DB, error)) error {
+	ctx, cancel := context.WithTimeout(db.ctx, 30*time.Second)
+	defer cancel()
+
+	// Wait for any running transactions to complete, and hold off new ones until they can run on the new database.
+	db.handleMu.Lock()
+	defer db.handleMu.Unlock()
+
+	if !db.IsEmbedded() {
+		return nil
+	}
+
+	embedded := db.db
+	embeddedStmts := db.stmts
+
+	// Also hold a write transaction on the embedded database until it is replaced, so nothing else can write to it.
+	embeddedTx, err := embedded.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to begin embedded database transaction: %w", err)
+	}
+
+	defer func() { _ = embeddedTx.Rollback() }()
+
+	tables, err := dumpTables(ctx, embeddedTx)
+	if err != nil {
+		return err
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	app, sqlDB, err := bootstrap(ctx, reverter)
+	if err != nil {
+		return err
+	}
+
+	reverter.Add(func() { _ = sqlDB.Close() })
+
+	_, err = db.schema.Ensure(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	stmts, err := cluster.PrepareStmts(sqlDB, project, false)
+	if err != nil {
+		return err
+	}
+
+	reverter.Add(func() { stmts.Close() })
+
+	err = cluster.Transaction(ctx, sqlDB, stmts, func(ctx context.Context, tx *sql.Tx) error {
+		return restoreTables(ctx, tx, tables)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to copy embedded database to dqlite: %w", err)
+	}
+
+	db.dqlite = app
+	db.db = sqlDB
+	db.stmts = stmts
+	db.embedded.Store(false)
+	reverter.Success()
+
+	_ = embeddedTx.Rollback()
+	embeddedStmts.Close()
+	err = embedded.Close()
+	if err != nil {
+		logger.Warn("Failed to close embedded database", logger.Ctx{"error": err})
+	}
+
+	err = os.Remove(db.os.EmbeddedDatabasePath())
+	if err != nil {
+		logger.Warn("Failed to remove embedded database", logger.Ctx{"error": err})
+	}
+
+	logger.Info("Converted embedded database to dqlite", logger.Ctx{"address": db.listenAddr.String(), "tables": len(tables)})
+
+	return nil
+}
+
+// table holds the rows of a database table.
+type table struct {
+	name    string
+	columns []string
+	rows    [][]any
+}
+
+// dumpTables reads the rows of every table other than the schema table, in the order the tables were created so that
+// foreign keys can be satisfied when they are restored.
+func dumpTables(ctx context.Context, tx *sql.Tx) ([]table, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schemas' ORDER BY rowid")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list embedded database tables: %w", err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]table, 0, len(names))
+	for _, name := range names {
+		t, err := dumpTable(ctx, tx, name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read embedded database table %q: %w", name, err)
+		}
+
+		tables = append(tables, *t)
+	}
+
+	return tables, nil
+}
+
+// dumpTable reads all rows of the named table.
+func dumpTable(ctx context.Context, tx *sql.Tx, name string) (*table, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %q", name))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	t := &table{name: name}
+	t.columns, err = rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		values := make([]any, len(t.columns))
+		pointers := make([]any, len(t.columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err := rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		t.rows = append(t.rows, values)
+	}
+
+	return t, rows.Err()
+}
+
+// restoreTables inserts the rows of each table.
+func restoreTables(ctx context.Context, tx *sql.Tx, tables []table) error {
+	for _, t := range tables {
+		if len(t.rows) == 0 {
+			continue
+		}
+
+		columns := make([]string, 0, len(t.columns))
+		for _, column := range t.columns {
+			columns = append(columns, fmt.Sprintf("%q", column))
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		stmt := fmt.Sprintf("INSERT INTO %q (%s) VALUES (%s)", t.name, strings.Join(columns, ", "), placeholders)
+		for _, row := range t.rows {
+			_, err := tx.ExecContext(ctx, stmt, row...)
+			if err != nil {
+				return fmt.Errorf("Failed to insert into table %q: %w", t.name, err)
+			}
+		}
+	}
+
+	return nil
+}
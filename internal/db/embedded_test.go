@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	dqlite "github.com/canonical/go-dqlite/app"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/revert"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// Ensures an embedded database keeps its contents across restarts.
+func (s *dbSuite) Test_embedded() {
+	os, err := sys.DefaultOS(s.T().TempDir(), "", true)
+	s.NoError(err)
+
+	ext, err := extensions.NewExtensionRegistry(true)
+	s.NoError(err)
+
+	ctx := context.Background()
+	addr := *api.NewURL().Host("10.0.0.0:8443")
+
+	db := NewDB(ctx, nil, nil, os, nil, clock.New())
+	db.SetSchema(nil, ext)
+	err = db.BootstrapEmbedded(ext, cluster.GetCallerProject(), addr, cluster.InternalClusterMember{Name: "member0", Address: addr.URL.Host, Certificate: "test-cert", Role: cluster.Pending})
+	s.NoError(err)
+	s.True(db.IsOpen())
+	s.True(db.IsEmbedded())
+
+	_, err = db.Leader(ctx)
+	s.ErrorIs(err, ErrEmbedded)
+	s.NoError(db.Stop())
+
+	db = NewDB(ctx, nil, nil, os, nil, clock.New())
+	db.SetSchema(nil, ext)
+	s.NoError(db.StartEmbedded(ext, cluster.GetCallerProject(), addr))
+	defer func() { s.NoError(db.Stop()) }()
+
+	var member *cluster.InternalClusterMember
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		member, err = cluster.GetInternalClusterMember(ctx, tx, "member0")
+		return err
+	})
+	s.NoError(err)
+	s.Equal("voter", string(member.Role))
+}
+
+// Ensures the contents of the embedded database can be copied to another database with the same schema.
+func (s *dbSuite) Test_embeddedCopy() {
+	ctx := context.Background()
+	source, err := NewTestDB(nil)
+	s.NoError(err)
+
+	target, err := NewTestDB(nil)
+	s.NoError(err)
+
+	ext, err := extensions.NewExtensionRegistry(true)
+	s.NoError(err)
+
+	heartbeat := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = source.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, name := range []string{"member0", "member1"} {
+			_, err := cluster.CreateInternalClusterMember(ctx, tx, cluster.InternalClusterMember{Name: name, Address: name + ":8443", Certificate: name, APIExtensions: ext, Heartbeat: heartbeat, Role: "voter"})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := cluster.CreateInternalTokenRecord(ctx, tx, cluster.InternalTokenRecord{Name: "member2", Secret: "secret"})
+
+		return err
+	})
+	s.NoError(err)
+
+	var tables []table
+	err = source.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		tables, err = dumpTables(ctx, tx)
+		return err
+	})
+	s.NoError(err)
+
+	for _, t := range tables {
+		s.NotEqual("schemas", t.name)
+	}
+
+	err = target.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return restoreTables(ctx, tx, tables)
+	})
+	s.NoError(err)
+
+	err = target.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		members, err := cluster.GetInternalClusterMembers(ctx, tx)
+		s.NoError(err)
+		s.Len(members, 2)
+		s.True(heartbeat.Equal(members[0].Heartbeat))
+
+		tokens, err := cluster.GetInternalTokenRecords(ctx, tx)
+		s.NoError(err)
+		s.Len(tokens, 1)
+
+		return nil
+	})
+	s.NoError(err)
+}
+
+// Ensures writes made while the embedded database is being converted all end up in the new database.
+func (s *dbSuite) Test_embeddedConvertConcurrentWrites() {
+	os, err := sys.DefaultOS(s.T().TempDir(), "", true)
+	s.NoError(err)
+
+	ext, err := extensions.NewExtensionRegistry(true)
+	s.NoError(err)
+
+	ctx := context.Background()
+	addr := *api.NewURL().Host("10.0.0.0:8443")
+
+	db := NewDB(ctx, nil, nil, os, nil, clock.NewFake(time.Now()))
+	db.SetSchema(nil, ext)
+	err = db.BootstrapEmbedded(ext, cluster.GetCallerProject(), addr, cluster.InternalClusterMember{Name: "member0", Address: addr.URL.Host, Certificate: "test-cert", Role: cluster.Pending})
+	s.NoError(err)
+	defer func() { s.NoError(db.Stop()) }()
+
+	// Stand in for dqlite with another SQLite database.
+	bootstrap := func(ctx context.Context, reverter *revert.Reverter) (*dqlite.App, *sql.DB, error) {
+		sqlDB, err := sql.Open("sqlite3", "file:"+filepath.Join(s.T().TempDir(), "converted.db")+"?_foreign_keys=1")
+		return nil, sqlDB, err
+	}
+
+	writers := 4
+	writes := 25
+	wg := sync.WaitGroup{}
+	startCh := make(chan struct{})
+	errs := make(chan error, writers*writes)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-startCh
+			for j := 0; j < writes; j++ {
+				errs <- db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+					_, err := cluster.CreateInternalTokenRecord(ctx, tx, cluster.InternalTokenRecord{Name: fmt.Sprintf("member-%d-%d", i, j), Secret: fmt.Sprintf("secret-%d-%d", i, j)})
+					return err
+				})
+			}
+		}(i)
+	}
+
+	close(startCh)
+	s.NoError(db.convertEmbedded(cluster.GetCallerProject(), bootstrap))
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	s.False(db.IsEmbedded())
+	s.NoFileExists(os.EmbeddedDatabasePath())
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		tokens, err := cluster.GetInternalTokenRecords(ctx, tx)
+		s.Len(tokens, writers*writes)
+
+		return err
+	})
+	s.NoError(err)
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// CheckLeader asks dqlite whether this member is currently the leader. An embedded database is always the leader.
+func (db *DB) CheckLeader(ctx context.Context) (bool, error) {
+	if db.IsEmbedded() {
+		return true, nil
+	}
+
+	leaderClient, err := db.Leader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer leaderClient.Close()
+
+	leader, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return leader != nil && leader.Address == db.listenAddr.URL.Host, nil
+}
+
+// IsLeader returns whether this member was the dqlite leader as of the last leadership check.
+func (db *DB) IsLeader() bool {
+	leader, _ := db.Leadership()
+
+	return leader
+}
+
+// Leadership returns whether this member was the dqlite leader as of the last leadership check, and a channel that is
+// closed as soon as that changes. Goroutines that must only run on the leader can wait on the channel to stop.
+func (db *DB) Leadership() (bool, <-chan struct{}) {
+	if db == nil {
+		return false, nil
+	}
+
+	db.leaderMu.Lock()
+	defer db.leaderMu.Unlock()
+
+	return db.leader, db.leaderCh
+}
+
+// OnLeaderChange sets a function to run each time this member gains or loses dqlite leadership.
+func (db *DB) OnLeaderChange(f func(leader bool)) {
+	db.leaderMu.Lock()
+	defer db.leaderMu.Unlock()
+
+	db.onLeaderChange = f
+}
+
+// setLeader records the result of a leadership check, notifying any waiters and running the change hook if it differs
+// from the last result.
+func (db *DB) setLeader(leader bool) {
+	if leader {
+		db.role.Store("leader")
+	} else {
+		db.role.Store("follower")
+	}
+
+	db.leaderMu.Lock()
+	if db.leader == leader {
+		db.leaderMu.Unlock()
+		return
+	}
+
+	db.leader = leader
+	close(db.leaderCh)
+	db.leaderCh = make(chan struct{})
+	onLeaderChange := db.onLeaderChange
+	db.leaderMu.Unlock()
+
+	logger.Info("Dqlite leadership changed", logger.Ctx{"address": db.listenAddr.String(), "leader": leader})
+
+	if onLeaderChange != nil {
+		onLeaderChange(leader)
+	}
+}
+
+// loopLeader checks for dqlite leadership changes every 5 seconds until the database is stopped. This member is
+// considered a follower whenever the check fails or times out.
+func (db *DB) loopLeader(check func(ctx context.Context) (bool, error)) {
+	for {
+		if db.IsOpen() {
+			ctx, cancel := context.WithTimeout(db.ctx, 5*time.Second)
+			leader, err := check(ctx)
+			cancel()
+
+			// A leader that can't confirm its leadership, e.g. because it is cut off from the rest of the cluster,
+			// steps down so that its leader-bound goroutines stop before another member is elected.
+			if err != nil && db.ctx.Err() == nil {
+				logger.Warn("Failed to check dqlite leadership", logger.Ctx{"address": db.listenAddr.String(), "error": err})
+				db.setLeader(false)
+			} else if err == nil {
+				db.setLeader(leader)
+			}
+		}
+
+		select {
+		case <-db.ctx.Done():
+			return
+		case <-db.clock.After(5 * time.Second):
+		}
+	}
+}
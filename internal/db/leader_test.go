@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// Ensures leadership changes are reported to the hook and to goroutines waiting on the leadership channel.
+func (s *dbSuite) Test_loopLeader() {
+	os, err := sys.DefaultOS(s.T().TempDir(), "", true)
+	s.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fakeClock := clock.NewFake(time.Now())
+	db := NewDB(ctx, nil, nil, os, nil, fakeClock)
+	db.openCanceller.Cancel()
+
+	changes := make(chan bool, 2)
+	db.OnLeaderChange(func(leader bool) { changes <- leader })
+
+	var leader atomic.Bool
+	var failing atomic.Bool
+	check := func(ctx context.Context) (bool, error) {
+		if failing.Load() {
+			return false, errors.New("No leader")
+		}
+
+		return leader.Load(), nil
+	}
+
+	isLeader, changed := db.Leadership()
+	s.False(isLeader)
+	s.Equal("", db.Role())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		db.loopLeader(check)
+	}()
+
+	// The first check finds this member is a follower, which is not a change.
+	fakeClock.BlockUntil(1)
+	s.Equal("follower", db.Role())
+
+	leader.Store(true)
+	fakeClock.Advance(5 * time.Second)
+	s.True(<-changes)
+	<-changed
+	s.True(db.IsLeader())
+	s.Equal("leader", db.Role())
+
+	_, changed = db.Leadership()
+	fakeClock.BlockUntil(1)
+	leader.Store(false)
+	fakeClock.Advance(5 * time.Second)
+	s.False(<-changes)
+	<-changed
+	s.False(db.IsLeader())
+
+	// A leader that fails to check its leadership steps down.
+	leader.Store(true)
+	_, changed = db.Leadership()
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Second)
+	s.True(<-changes)
+	<-changed
+
+	_, changed = db.Leadership()
+	fakeClock.BlockUntil(1)
+	failing.Store(true)
+	fakeClock.Advance(5 * time.Second)
+	s.False(<-changes)
+	<-changed
+	s.False(db.IsLeader())
+
+	cancel()
+	<-done
+	s.Empty(changes)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/canonical/lxd/lxd/db/schema"
 
@@ -36,6 +37,8 @@ func NewSchema() *SchemaUpdateManager {
 			updateFromV1,
 			updateFromV2,
 			mgr.updateFromV3,
+			updateFromV4,
+			updateFromV5,
 		},
 	}
 
@@ -73,6 +76,39 @@ func (s *SchemaUpdateManager) AppendSchema(schemaExtensions []schema.Update, api
 	s.apiExtensions = apiExtensions
 }
 
+// updateFromV5 introduces the internal_client_certificates table, holding the certificates of clients outside of the
+// cluster that are trusted to use its API.
+func updateFromV5(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_client_certificates (
+  id                   INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name                 TEXT     NOT      NULL,
+  fingerprint          TEXT     NOT      NULL,
+  certificate          TEXT     NOT      NULL,
+  created_at           DATETIME NOT      NULL,
+  UNIQUE(name),
+  UNIQUE(fingerprint)
+);
+`
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV4 introduces a creation time as a column on the internal_cluster_members table, so that pending cluster
+// members left behind by a failed join can be cleaned up. Existing cluster members are considered to be created now.
+func updateFromV4(ctx context.Context, tx *sql.Tx) error {
+	stmt := "ALTER TABLE internal_cluster_members ADD COLUMN created_at DATETIME NOT NULL DEFAULT '0001-01-01 00:00:00+00:00'"
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE internal_cluster_members SET created_at=?", time.Now())
+
+	return err
+}
+
 // updateFromV3 auto-applies the initial set of API extensions to the internal_cluster_members table.
 // This is done so that the cluster won't have to be notified twice,
 // once for the schema update that introduces API extensions to be applied,
@@ -0,0 +1,63 @@
+package endpoints
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/canonical/lxd/shared/logger"
+	"golang.org/x/sys/unix"
+)
+
+// ctxPeerCredentials is the request context key for the credentials of a unix socket peer.
+type ctxPeerCredentials struct{}
+
+// PeerCredentials identifies the process on the other end of a unix socket connection.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// Privileged returns whether the peer runs as root, or as the same user as the daemon.
+func (c PeerCredentials) Privileged() bool {
+	return c.UID == 0 || int(c.UID) == os.Getuid()
+}
+
+// GetPeerCredentials returns the credentials of the unix socket peer that sent the request with the given context,
+// or nil if the request was not received over the unix socket.
+func GetPeerCredentials(ctx context.Context) *PeerCredentials {
+	creds, _ := ctx.Value(ctxPeerCredentials{}).(*PeerCredentials)
+
+	return creds
+}
+
+// withPeerCredentials records the credentials of the peer of a unix socket connection in the connection's context.
+func withPeerCredentials(ctx context.Context, conn net.Conn) context.Context {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		logger.Warn("Failed to get unix socket peer credentials", logger.Ctx{"error": err})
+		return ctx
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+
+	if err != nil {
+		logger.Warn("Failed to get unix socket peer credentials", logger.Ctx{"error": err})
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxPeerCredentials{}, &PeerCredentials{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid})
+}
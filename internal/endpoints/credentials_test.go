@@ -0,0 +1,35 @@
+package endpoints
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerCredentials(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.socket"))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	creds := GetPeerCredentials(withPeerCredentials(context.Background(), conn))
+	require.NotNil(t, creds)
+	require.Equal(t, int32(os.Getpid()), creds.PID)
+	require.Equal(t, uint32(os.Getuid()), creds.UID)
+	require.Equal(t, uint32(os.Getgid()), creds.GID)
+	require.True(t, creds.Privileged())
+
+	require.False(t, PeerCredentials{UID: uint32(os.Getuid()) + 1}.Privileged())
+	require.Nil(t, GetPeerCredentials(context.Background()))
+}
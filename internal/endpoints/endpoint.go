@@ -5,6 +5,7 @@ type Endpoint interface {
 	Listen() error
 	Serve()
 	Close() error
+	Serving() bool
 	Type() EndpointType
 }
 
@@ -102,6 +102,19 @@ func (e *Endpoints) up(listeners map[EndpointType]Endpoint) error {
 	return nil
 }
 
+// Status returns whether each of the configured listeners is currently serving.
+func (e *Endpoints) Status() map[EndpointType]bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	status := make(map[EndpointType]bool, len(e.listeners))
+	for endpointType, listener := range e.listeners {
+		status[endpointType] = listener.Serving()
+	}
+
+	return status
+}
+
 // Down closes all of the configured listeners, or any for the type specifically supplied.
 func (e *Endpoints) Down(types ...EndpointType) error {
 	e.mu.Lock()
@@ -23,6 +23,8 @@ type Network struct {
 	listener net.Listener
 	server   *http.Server
 
+	wrap func(net.Listener) net.Listener // Wraps the TCP listener before TLS is applied, if set.
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -42,6 +44,11 @@ func NewNetwork(ctx context.Context, endpointType EndpointType, server *http.Ser
 	}
 }
 
+// WrapListener sets a function that wraps the TCP listener once it is opened, before TLS is applied.
+func (n *Network) WrapListener(wrap func(net.Listener) net.Listener) {
+	n.wrap = wrap
+}
+
 // Type returns the type of the Endpoint.
 func (n *Network) Type() EndpointType {
 	return n.networkType
@@ -66,6 +73,10 @@ func (n *Network) Listen() error {
 		return fmt.Errorf("Failed to listen on https socket: %w", err)
 	}
 
+	if n.wrap != nil {
+		listener = n.wrap(listener)
+	}
+
 	n.listener = listeners.NewFancyTLSListener(listener, n.cert)
 
 	return nil
@@ -107,6 +118,11 @@ func (n *Network) Serve() {
 	}()
 }
 
+// Serving returns whether the listener is open and has not been closed.
+func (n *Network) Serving() bool {
+	return n.listener != nil && n.ctx.Err() == nil
+}
+
 // Close the listener.
 func (n *Network) Close() error {
 	if n.listener == nil {
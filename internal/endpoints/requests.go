@@ -0,0 +1,75 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Requests tracks the API requests that are currently being handled, so that they can be drained on shutdown.
+type Requests struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	doneCh   chan struct{} // Closed once draining has started and no requests remain.
+}
+
+// NewRequests returns a new request tracker that accepts requests until Drain is called.
+func NewRequests() *Requests {
+	return &Requests{doneCh: make(chan struct{})}
+}
+
+// Begin registers a new in-flight request and returns a function to call once it has been handled.
+// If the tracker is draining, no request is registered and false is returned.
+func (r *Requests) Begin() (done func(), ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return nil, false
+	}
+
+	r.count++
+
+	return sync.OnceFunc(r.end), true
+}
+
+// end removes a request from the tracker.
+func (r *Requests) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count--
+	if r.draining && r.count == 0 {
+		close(r.doneCh)
+	}
+}
+
+// Count returns the number of requests currently being handled.
+func (r *Requests) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.count
+}
+
+// Drain stops the tracker from accepting new requests, and blocks until all in-flight requests have been handled
+// or the context is cancelled.
+func (r *Requests) Drain(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.draining {
+		r.draining = true
+		if r.count == 0 {
+			close(r.doneCh)
+		}
+	}
+
+	r.mu.Unlock()
+
+	select {
+	case <-r.doneCh:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out waiting for %d in-flight requests: %w", r.Count(), ctx.Err())
+	}
+}
@@ -0,0 +1,58 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestsDrain(t *testing.T) {
+	requests := NewRequests()
+
+	done, ok := requests.Begin()
+	require.True(t, ok)
+	assert.Equal(t, 1, requests.Count())
+
+	// Drain should time out while a request is still in-flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := requests.Drain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// No new requests are accepted once draining has started.
+	_, ok = requests.Begin()
+	assert.False(t, ok)
+
+	drainErr := make(chan error)
+	go func() {
+		drainErr <- requests.Drain(context.Background())
+	}()
+
+	// Calling done more than once should only end the request once.
+	done()
+	done()
+
+	select {
+	case err := <-drainErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain did not return after the in-flight request ended")
+	}
+
+	assert.Equal(t, 0, requests.Count())
+}
+
+func TestRequestsDrainIdle(t *testing.T) {
+	requests := NewRequests()
+
+	err := requests.Drain(context.Background())
+	assert.NoError(t, err)
+
+	// Draining again should not block.
+	err = requests.Drain(context.Background())
+	assert.NoError(t, err)
+}
@@ -28,6 +28,16 @@ type Socket struct {
 
 // NewSocket returns a Socket struct with no listener attached yet.
 func NewSocket(ctx context.Context, server *http.Server, path api.URL, group string) *Socket {
+	// Record the credentials of the peer of each connection, so requests can be authorized by user.
+	connContext := server.ConnContext
+	server.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, conn)
+		}
+
+		return withPeerCredentials(ctx, conn)
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	return &Socket{
 		Path:  path.Hostname(),
@@ -106,6 +116,11 @@ func (s *Socket) Serve() {
 	}()
 }
 
+// Serving returns whether the Socket's listener is open and has not been closed.
+func (s *Socket) Serving() bool {
+	return s.listener != nil && s.ctx.Err() == nil
+}
+
 // Close the Socket's listener.
 func (s *Socket) Close() error {
 	if s.listener == nil {
@@ -0,0 +1,116 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// executableTimeout is how long each hook executable may run, unless the hook's policy times out sooner.
+const executableTimeout = 5 * time.Minute
+
+// RunExecutables runs each executable file in the directory in lexical order, passing the event as JSON on stdin and
+// in environment variables. The output of each executable is logged. Running stops at the first executable that
+// fails. Nothing is run if the directory doesn't exist.
+func RunExecutables(ctx context.Context, dir string, event types.HookEvent) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("Failed to read hook directory %q: %w", dir, err)
+	}
+
+	stdin, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("Failed to encode hook event: %w", err)
+	}
+
+	env := append(os.Environ(), eventEnv(event)...)
+
+	// Entries are already sorted by name.
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+
+	for _, path := range paths {
+		err := runExecutable(ctx, path, stdin, env, event.Hook)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runExecutable runs a single hook executable, logging its output.
+func runExecutable(ctx context.Context, path string, stdin []byte, env []string, hook types.HookType) error {
+	ctx, cancel := context.WithTimeout(ctx, executableTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	// Don't wait forever for any children of a killed executable that still hold its output open.
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		logger.Info("Hook executable output", logger.Ctx{"hook": hook, "executable": path, "output": scanner.Text()})
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Hook executable %q was stopped: %w", path, ctx.Err())
+		}
+
+		return fmt.Errorf("Hook executable %q failed: %w", path, err)
+	}
+
+	return nil
+}
+
+// eventEnv returns the environment variables describing the event to hook executables.
+func eventEnv(event types.HookEvent) []string {
+	env := []string{
+		"MICROCLUSTER_HOOK=" + string(event.Hook),
+		"MICROCLUSTER_NAME=" + event.Name,
+		"MICROCLUSTER_ADDRESS=" + event.Address,
+		"MICROCLUSTER_INITIATOR=" + event.Initiator,
+		"MICROCLUSTER_FORCE=" + strconv.FormatBool(event.Force),
+	}
+
+	if event.Member != nil {
+		env = append(env,
+			"MICROCLUSTER_MEMBER_NAME="+event.Member.Name,
+			"MICROCLUSTER_MEMBER_ADDRESS="+event.Member.Address.String(),
+		)
+	}
+
+	return env
+}
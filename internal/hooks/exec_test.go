@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// writeExecutable writes a shell script to the directory with the given mode.
+func writeExecutable(t *testing.T, dir string, name string, script string, mode os.FileMode) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), mode)
+	require.NoError(t, err)
+}
+
+func TestRunExecutables(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	// Executables run in lexical order, and receive the event on stdin and in the environment.
+	writeExecutable(t, dir, "10-event", `cat > "`+out+`/event.json"; echo "$MICROCLUSTER_HOOK $MICROCLUSTER_MEMBER_NAME" > "`+out+`/env"`, 0755)
+	writeExecutable(t, dir, "20-order", `files=$(ls "`+out+`"); echo "$files" > "`+out+`/order"; echo "some output"`, 0755)
+	writeExecutable(t, dir, "30-skipped", `touch "`+out+`/skipped"`, 0644)
+
+	event := types.HookEvent{Hook: types.PostJoin, Name: "member0", Member: &types.ClusterMemberLocal{Name: "member1"}, Force: true}
+	require.NoError(t, RunExecutables(context.Background(), dir, event))
+
+	content, err := os.ReadFile(filepath.Join(out, "event.json"))
+	require.NoError(t, err)
+
+	var received map[string]any
+	require.NoError(t, json.Unmarshal(content, &received))
+	require.Equal(t, "post-join", received["hook"])
+	require.Equal(t, "member0", received["name"])
+	require.Equal(t, true, received["force"])
+
+	content, err = os.ReadFile(filepath.Join(out, "env"))
+	require.NoError(t, err)
+	require.Equal(t, "post-join member1\n", string(content))
+
+	content, err = os.ReadFile(filepath.Join(out, "order"))
+	require.NoError(t, err)
+	require.Equal(t, "env\nevent.json\n", string(content))
+
+	require.NoFileExists(t, filepath.Join(out, "skipped"))
+
+	// A missing directory runs nothing.
+	require.NoError(t, RunExecutables(context.Background(), filepath.Join(dir, "missing"), event))
+}
+
+func TestRunExecutablesFailure(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	// Running stops at the first failure.
+	writeExecutable(t, dir, "10-fail", "echo failing; exit 1", 0755)
+	writeExecutable(t, dir, "20-after", `touch "`+out+`/after"`, 0755)
+
+	err := RunExecutables(context.Background(), dir, types.HookEvent{Hook: types.PostRemove})
+	require.ErrorContains(t, err, "10-fail")
+	require.NoFileExists(t, filepath.Join(out, "after"))
+}
+
+func TestRunExecutablesCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutable(t, dir, "10-sleep", "exec sleep 60", 0755)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := RunExecutables(ctx, dir, types.HookEvent{Hook: types.PreShutdown})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
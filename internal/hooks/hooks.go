@@ -0,0 +1,207 @@
+// Package hooks runs the daemon's hooks and hook executables according to their configured timeout, retry and failure
+// policies, and records the result of each run.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// FailurePolicy decides what happens when a hook has failed every attempt.
+type FailurePolicy string
+
+const (
+	// FailureAbort returns the error of a failed hook to the operation that ran it, which stops and reverts what it
+	// can. For example, a member whose PostJoin hook fails is removed from the cluster again. This is the default.
+	FailureAbort FailurePolicy = "abort"
+
+	// FailureWarn logs the error of a failed hook and carries on with the operation.
+	FailureWarn FailurePolicy = "warn"
+)
+
+// Policy configures how a hook is run.
+type Policy struct {
+	// Timeout cancels the context of a hook attempt and stops waiting for it after the given duration, killing any
+	// hook executables that are still running. Hooks that don't respect their context are left to finish in the
+	// background. No timeout is applied if zero.
+	Timeout time.Duration
+
+	// Retries is the number of times to retry a failed hook before applying the failure policy. Retries stop once
+	// the daemon is shutting down.
+	Retries int
+
+	// RetryDelay is the time to wait before the first retry, which doubles for each following retry.
+	// Defaults to one second.
+	RetryDelay time.Duration
+
+	// OnFailure decides what happens once every attempt has failed. Defaults to FailureAbort.
+	OnFailure FailurePolicy
+
+	// Async runs the hook in the background, so the operation that ran it carries on straight away. Failures of
+	// asynchronous hooks are only logged and recorded.
+	Async bool
+}
+
+// maxRuns is the number of recent hook runs that are recorded.
+const maxRuns = 100
+
+// Runner runs hooks according to their policies.
+type Runner struct {
+	clock    clock.Clock
+	policies map[types.HookType]Policy
+
+	mu   sync.Mutex
+	runs []types.HookRun // Most recent last.
+
+	wg sync.WaitGroup // Asynchronous hooks that are still running.
+}
+
+// NewRunner returns a Runner for hooks with the given policies. Hooks without a policy run once, synchronously and
+// without a timeout.
+func NewRunner(clock clock.Clock, policies map[types.HookType]Policy) (*Runner, error) {
+	for hook, policy := range policies {
+		if policy.Timeout < 0 || policy.Retries < 0 || policy.RetryDelay < 0 {
+			return nil, fmt.Errorf("Invalid policy for hook %q: Timeout, retries and retry delay must not be negative", hook)
+		}
+
+		switch policy.OnFailure {
+		case "", FailureAbort, FailureWarn:
+		default:
+			return nil, fmt.Errorf("Invalid policy for hook %q: Unknown failure policy %q", hook, policy.OnFailure)
+		}
+	}
+
+	return &Runner{clock: clock, policies: policies}, nil
+}
+
+// Run runs the hook according to its policy. The member is the cluster member the hook concerns, if any. An error is
+// only returned if the hook failed and its failure policy is FailureAbort.
+func (r *Runner) Run(ctx context.Context, hook types.HookType, member string, f func(ctx context.Context) error) error {
+	policy := r.policies[hook]
+	if !policy.Async {
+		return r.run(ctx, hook, member, policy, f)
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		_ = r.run(ctx, hook, member, policy, f)
+	}()
+
+	return nil
+}
+
+// run runs the hook until it succeeds or runs out of retries, and records the result.
+func (r *Runner) run(ctx context.Context, hook types.HookType, member string, policy Policy, f func(ctx context.Context) error) error {
+	run := types.HookRun{Hook: hook, Member: member, Async: policy.Async, StartedAt: r.clock.Now()}
+
+	delay := policy.RetryDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for {
+		run.Attempts++
+		err = r.attempt(ctx, policy.Timeout, f)
+		if err == nil || run.Attempts > policy.Retries {
+			break
+		}
+
+		logger.Warn("Retrying failed hook", logger.Ctx{"hook": hook, "attempt": run.Attempts, "delay": delay, "error": err})
+
+		select {
+		case <-ctx.Done():
+		case <-r.clock.After(delay):
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		delay *= 2
+	}
+
+	run.FinishedAt = r.clock.Now()
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	r.record(run)
+
+	if err == nil {
+		return nil
+	}
+
+	if policy.Async || policy.OnFailure == FailureWarn {
+		logger.Warn("Ignoring failed hook", logger.Ctx{"hook": hook, "attempts": run.Attempts, "error": err})
+		return nil
+	}
+
+	return err
+}
+
+// attempt runs the hook once, giving up on it after the timeout if one is set.
+func (r *Runner) attempt(ctx context.Context, timeout time.Duration, f func(ctx context.Context) error) error {
+	if timeout == 0 {
+		return f(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- f(ctx) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-r.clock.After(timeout):
+		return fmt.Errorf("Hook timed out after %s", timeout)
+	}
+}
+
+// record adds the run to the list of recent runs.
+func (r *Runner) record(run types.HookRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs = append(r.runs, run)
+	if len(r.runs) > maxRuns {
+		r.runs = r.runs[len(r.runs)-maxRuns:]
+	}
+}
+
+// Runs returns the most recent hook runs, oldest first.
+func (r *Runner) Runs() []types.HookRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runs := make([]types.HookRun, len(r.runs))
+	copy(runs, r.runs)
+
+	return runs
+}
+
+// Wait blocks until all asynchronous hooks have finished, or the context is cancelled.
+func (r *Runner) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+func TestNewRunner(t *testing.T) {
+	_, err := NewRunner(clock.New(), map[types.HookType]Policy{types.PostJoin: {Retries: -1}})
+	require.Error(t, err)
+
+	_, err = NewRunner(clock.New(), map[types.HookType]Policy{types.PostJoin: {OnFailure: "ignore"}})
+	require.Error(t, err)
+
+	_, err = NewRunner(clock.New(), map[types.HookType]Policy{types.PostJoin: {OnFailure: FailureWarn, Timeout: time.Second}})
+	require.NoError(t, err)
+}
+
+func TestRunnerRetries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFake(start)
+	r, err := NewRunner(fakeClock, map[types.HookType]Policy{
+		types.PostJoin:   {Retries: 2, RetryDelay: time.Second},
+		types.PostRemove: {Retries: 1, OnFailure: FailureWarn},
+	})
+	require.NoError(t, err)
+
+	// The hook succeeds on its last attempt, after waiting 1s and then 2s.
+	var attempts atomic.Int32
+	errCh := make(chan error)
+	go func() {
+		errCh <- r.Run(context.Background(), types.PostJoin, "member0", func(ctx context.Context) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("Failed")
+			}
+
+			return nil
+		})
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(time.Second)
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(2 * time.Second)
+	require.NoError(t, <-errCh)
+
+	// A hook that keeps failing with the warn policy doesn't return its error.
+	go func() {
+		errCh <- r.Run(context.Background(), types.PostRemove, "member1", func(ctx context.Context) error { return errors.New("Failed") })
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(time.Second)
+	require.NoError(t, <-errCh)
+
+	// Hooks without a policy run once, and abort on failure.
+	err = r.Run(context.Background(), types.OnStart, "", func(ctx context.Context) error { return errors.New("Failed") })
+	require.EqualError(t, err, "Failed")
+
+	runs := r.Runs()
+	require.Len(t, runs, 3)
+	require.Equal(t, types.HookRun{Hook: types.PostJoin, Member: "member0", StartedAt: start, FinishedAt: start.Add(3 * time.Second), Attempts: 3}, runs[0])
+	require.Equal(t, types.PostRemove, runs[1].Hook)
+	require.Equal(t, 2, runs[1].Attempts)
+	require.Equal(t, "Failed", runs[1].Error)
+	require.Equal(t, types.OnStart, runs[2].Hook)
+	require.Equal(t, 1, runs[2].Attempts)
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	fakeClock := clock.NewFake(time.Now())
+	r, err := NewRunner(fakeClock, map[types.HookType]Policy{types.PostRemove: {Timeout: time.Minute}})
+	require.NoError(t, err)
+
+	cancelled := make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- r.Run(context.Background(), types.PostRemove, "member1", func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+
+			return ctx.Err()
+		})
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(time.Minute)
+	require.EqualError(t, <-errCh, "Hook timed out after 1m0s")
+	<-cancelled
+}
+
+func TestRunnerAsync(t *testing.T) {
+	r, err := NewRunner(clock.New(), map[types.HookType]Policy{types.OnNewMember: {Async: true}})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	err = r.Run(context.Background(), types.OnNewMember, "member2", func(ctx context.Context) error {
+		<-release
+		return errors.New("Failed")
+	})
+	require.NoError(t, err)
+
+	// The hook is still running, so waiting gives up once the context is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, r.Wait(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, r.Wait(context.Background()))
+
+	runs := r.Runs()
+	require.Len(t, runs, 1)
+	require.True(t, runs[0].Async)
+	require.Equal(t, "Failed", runs[0].Error)
+}
+
+func TestRunnerRecordsRecentRuns(t *testing.T) {
+	r, err := NewRunner(clock.New(), nil)
+	require.NoError(t, err)
+
+	for i := 0; i < maxRuns+10; i++ {
+		require.NoError(t, r.Run(context.Background(), types.OnHeartbeat, "", func(ctx context.Context) error { return nil }))
+	}
+
+	require.Len(t, r.Runs(), maxRuns)
+}
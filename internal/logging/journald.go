@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// journaldFormatter formats log lines with a leading syslog priority, e.g. "<3>Failed to do something error=...".
+type journaldFormatter struct{}
+
+// Format implements logrus.Formatter.
+func (f *journaldFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var priority int
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		priority = 2
+	case logrus.ErrorLevel:
+		priority = 3
+	case logrus.WarnLevel:
+		priority = 4
+	case logrus.InfoLevel:
+		priority = 6
+	default:
+		priority = 7
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<%d>%s", priority, strings.ReplaceAll(entry.Message, "\n", " "))
+	for _, key := range keys {
+		value := fmt.Sprint(entry.Data[key])
+		if strings.ContainsAny(value, " \"=\n") {
+			value = fmt.Sprintf("%q", value)
+		}
+
+		fmt.Fprintf(buf, " %s=%s", key, value)
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/termios"
+	"github.com/sirupsen/logrus"
+	lWriter "github.com/sirupsen/logrus/hooks/writer"
+)
+
+// Format is the output format of log lines.
+type Format string
+
+const (
+	// FormatText writes human readable log lines with a timestamp, level, message, and context.
+	FormatText Format = "text"
+
+	// FormatJSON writes each log line as a JSON object.
+	FormatJSON Format = "json"
+
+	// FormatJournald writes log lines prefixed with their syslog priority, as understood by journald when reading
+	// the standard error of a service. The timestamp is omitted as journald records its own.
+	FormatJournald Format = "journald"
+)
+
+// Config holds the configuration of the daemon logger.
+type Config struct {
+	// File is the path of a file to write logs to, in addition to stderr.
+	File string
+
+	// MaxSize is the size in bytes after which the log file is rotated. The log file is not rotated if unset.
+	MaxSize int64
+
+	// MaxBackups is the number of rotated log files to keep.
+	MaxBackups int
+
+	// Format is the format of each log line. Defaults to FormatText.
+	Format Format
+
+	Verbose bool
+	Debug   bool
+}
+
+// ParseFormat returns the log format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON, FormatJournald:
+		return Format(name), nil
+	default:
+		return "", fmt.Errorf("Unknown log format %q", name)
+	}
+}
+
+// Init replaces the global logger with one configured by the given Config.
+func Init(config Config) error {
+	format, err := ParseFormat(string(config.Format))
+	if err != nil {
+		return err
+	}
+
+	log := logrus.New()
+	log.Level = logrus.DebugLevel
+	log.SetOutput(io.Discard)
+
+	switch format {
+	case FormatJSON:
+		log.Formatter = &logrus.JSONFormatter{}
+	case FormatJournald:
+		log.Formatter = &journaldFormatter{}
+	default:
+		log.Formatter = &logrus.TextFormatter{PadLevelText: true, FullTimestamp: true, ForceColors: config.File == "" && termios.IsTerminal(int(os.Stderr.Fd()))}
+	}
+
+	levels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+	if config.Debug {
+		levels = append(levels, logrus.InfoLevel, logrus.DebugLevel)
+	} else if config.Verbose {
+		levels = append(levels, logrus.InfoLevel)
+	}
+
+	writers := []io.Writer{os.Stderr}
+	if config.File != "" {
+		f, err := openRotatingFile(config.File, config.MaxSize, config.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("Failed to open log file %q: %w", config.File, err)
+		}
+
+		writers = append(writers, f)
+	}
+
+	log.AddHook(&lWriter.Hook{
+		Writer:    io.MultiWriter(writers...),
+		LogLevels: levels,
+	})
+
+	logger.Log = &logWrapper{target: log}
+
+	return nil
+}
+
+// logWrapper implements logger.Logger on top of a logrus logger or entry.
+type logWrapper struct {
+	target interface {
+		WithFields(fields logrus.Fields) *logrus.Entry
+	}
+}
+
+func (lw *logWrapper) entry(ctx []logger.Ctx) *logrus.Entry {
+	fields := logrus.Fields{}
+	for _, c := range ctx {
+		for k, v := range c {
+			fields[k] = v
+		}
+	}
+
+	return lw.target.WithFields(fields)
+}
+
+// Panic logs a message with panic level, and panics.
+func (lw *logWrapper) Panic(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Panic(msg)
+}
+
+// Fatal logs a message with fatal level, and exits.
+func (lw *logWrapper) Fatal(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Fatal(msg)
+}
+
+// Error logs a message with error level.
+func (lw *logWrapper) Error(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Error(msg)
+}
+
+// Warn logs a message with warning level.
+func (lw *logWrapper) Warn(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Warn(msg)
+}
+
+// Info logs a message with info level.
+func (lw *logWrapper) Info(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Info(msg)
+}
+
+// Debug logs a message with debug level.
+func (lw *logWrapper) Debug(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Debug(msg)
+}
+
+// Trace logs a message with trace level.
+func (lw *logWrapper) Trace(msg string, ctx ...logger.Ctx) {
+	lw.entry(ctx).Trace(msg)
+}
+
+// AddContext returns a logger that includes the given context with every message.
+func (lw *logWrapper) AddContext(ctx logger.Ctx) logger.Logger {
+	return &logWrapper{target: lw.entry([]logger.Ctx{ctx})}
+}
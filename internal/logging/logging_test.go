@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, f.Close())
+
+	expected := map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	}
+
+	for file, content := range expected {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+	}
+
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestJournaldFormatter(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.WarnLevel,
+		Message: "Something happened",
+		Data:    logrus.Fields{"request_id": "abc", "error": "not found"},
+	}
+
+	out, err := (&journaldFormatter{}).Format(entry)
+	require.NoError(t, err)
+	require.Equal(t, "<4>Something happened error=\"not found\" request_id=abc\n", string(out))
+}
+
+func TestInherit(t *testing.T) {
+	log := logger.AddContext(logger.Ctx{"request_id": "abc"})
+	requestCtx := WithLogger(WithRequestID(context.Background(), "abc"), log)
+
+	ctx := Inherit(context.Background(), requestCtx)
+	require.Equal(t, "abc", RequestID(ctx))
+	require.Equal(t, log, FromContext(ctx))
+
+	ctx = Inherit(context.Background(), context.Background())
+	require.Equal(t, "", RequestID(ctx))
+	require.Equal(t, logger.Log, FromContext(ctx))
+}
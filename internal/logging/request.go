@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// HeaderRequestID is the header used to propagate the ID of a request across cluster members.
+const HeaderRequestID = "X-Request-Id"
+
+type ctxKey string
+
+const (
+	ctxRequestID ctxKey = "request_id"
+	ctxLogger    ctxKey = "logger"
+)
+
+// WithRequestID returns a copy of the context that carries the given request ID.
+// Requests made with this context by the internal client will include the ID in their headers.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxRequestID, id)
+}
+
+// RequestID returns the request ID carried by the context, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID).(string)
+
+	return id
+}
+
+// WithLogger returns a copy of the context that carries the given logger.
+func WithLogger(ctx context.Context, log logger.Logger) context.Context {
+	return context.WithValue(ctx, ctxLogger, log)
+}
+
+// FromContext returns the logger carried by the context, or the global logger if there is none.
+func FromContext(ctx context.Context) logger.Logger {
+	log, ok := ctx.Value(ctxLogger).(logger.Logger)
+	if !ok {
+		return logger.Log
+	}
+
+	return log
+}
+
+// Inherit returns a copy of ctx that carries the request ID and logger of the request context from, if any.
+// This is used by handlers that perform work under a longer-lived context than that of the request itself.
+func Inherit(ctx context.Context, from context.Context) context.Context {
+	ctx = WithRequestID(ctx, RequestID(from))
+
+	log, ok := from.Value(ctxLogger).(logger.Logger)
+	if ok {
+		ctx = WithLogger(ctx, log)
+	}
+
+	return ctx
+}
@@ -5,16 +5,74 @@ import (
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/request"
+
+	"github.com/canonical/microcluster/internal/endpoints"
 )
 
-// TrustedRequest holds data pertaining to what level of trust we have for the request.
-type TrustedRequest struct {
-	Trusted bool
+// Authentication methods by which an Identity can be established.
+const (
+	// MethodUnix is used for requests over the local unix socket.
+	MethodUnix = "unix"
+
+	// MethodTLS is used for requests with a TLS client certificate found in the truststore.
+	MethodTLS = "tls"
+
+	// MethodClient is used for requests with a TLS client certificate trusted through the clients API.
+	MethodClient = "client"
+
+	// MethodBearer is used for requests with a static bearer token.
+	MethodBearer = "bearer"
+
+	// MethodOIDC is used for requests with an OpenID Connect token.
+	MethodOIDC = "oidc"
+)
+
+// Identity describes the authenticated sender of a request.
+type Identity struct {
+	// Username identifies the sender, e.g. the cluster member name for requests authenticated by TLS.
+	Username string
+
+	// Groups the sender belongs to, as reported by the authentication method.
+	Groups []string
+
+	// Method by which the sender was authenticated.
+	Method string
+
+	// Credentials of the sending process, for requests over the unix socket.
+	Credentials *endpoints.PeerCredentials
+}
+
+// Privileged returns whether the sender may perform mutating operations. Only root, or the user the daemon runs as,
+// is privileged over the unix socket, along with other cluster members and clients trusted through the clients API.
+// Clients are never cluster members though, so they are still refused by endpoints that only allow members. Senders
+// authenticated by other methods are not privileged, so they may only read unless an application's own access
+// handlers allow them more.
+func (i Identity) Privileged() bool {
+	switch i.Method {
+	case MethodUnix:
+		return i.Credentials != nil && i.Credentials.Privileged()
+	case MethodTLS, MethodClient:
+		return true
+	default:
+		return false
+	}
+}
+
+// Member returns whether the sender is another cluster member, authenticated by its certificate in the truststore.
+func (i Identity) Member() bool {
+	return i.Method == MethodTLS
 }
 
-// SetRequestAuthentication sets the trusted status for the request. A trusted request will be treated as having come from a trusted system.
-func SetRequestAuthentication(r *http.Request, trusted bool) *http.Request {
-	r = r.WithContext(context.WithValue(r.Context(), any(request.CtxAccess), TrustedRequest{Trusted: trusted}))
+// SetRequestIdentity records the identity of the request's sender. Requests with a nil identity are untrusted.
+func SetRequestIdentity(r *http.Request, identity *Identity) *http.Request {
+	r = r.WithContext(context.WithValue(r.Context(), any(request.CtxAccess), identity))
 
 	return r
 }
+
+// GetRequestIdentity returns the identity of the request's sender, or nil if the request is untrusted.
+func GetRequestIdentity(r *http.Request) *Identity {
+	identity, _ := r.Context().Value(request.CtxAccess).(*Identity)
+
+	return identity
+}
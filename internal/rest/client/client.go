@@ -22,6 +22,12 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/tcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/canonical/microcluster/internal/logging"
+	"github.com/canonical/microcluster/internal/tracing"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 // EndpointType is a type specifying the endpoint on with the resource exists.
@@ -47,8 +53,17 @@ type Client struct {
 	url api.URL
 }
 
+// DialFunc establishes the TCP connection to a cluster member, in place of a net.Dialer.
+type DialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
 // New returns a new client configured with the given url and certificates.
 func New(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool) (*Client, error) {
+	return NewWithDialer(url, clientCert, remoteCert, forwarding, nil)
+}
+
+// NewWithDialer returns a new client like New, whose connections to the url are established with the given dial
+// function if it is set.
+func NewWithDialer(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool, dial DialFunc) (*Client, error) {
 	var err error
 	var httpClient *http.Client
 
@@ -62,7 +77,7 @@ func New(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate,
 			proxy = forwardingProxy
 		}
 
-		httpClient, err = tlsHTTPClient(clientCert, remoteCert, proxy)
+		httpClient, err = tlsHTTPClient(clientCert, remoteCert, proxy, dial)
 	}
 
 	if err != nil {
@@ -107,7 +122,7 @@ func unixHTTPClient(path string) (*http.Client, error) {
 	return client, nil
 }
 
-func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, proxy func(req *http.Request) (*url.URL, error)) (*http.Client, error) {
+func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, proxy func(req *http.Request) (*url.URL, error), dial DialFunc) (*http.Client, error) {
 	var tlsConfig *tls.Config
 	if remoteCert != nil {
 		var err error
@@ -119,35 +134,25 @@ func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, pr
 
 	tlsDialContext := func(t *http.Transport) func(context.Context, string, string) (net.Conn, error) {
 		return func(ctx context.Context, network string, addr string) (net.Conn, error) {
-			host, port, err := net.SplitHostPort(addr)
+			hostPort, err := types.ParseHostPort(addr)
 			if err != nil {
 				return nil, err
 			}
 
-			addrs, err := net.LookupHost(host)
+			// Resolve the host on each dial, so that members addressed by DNS name follow any change of IP.
+			addrs, err := hostPort.Resolve(ctx)
 			if err != nil {
 				return nil, err
 			}
 
 			var lastErr error
 			for _, a := range addrs {
-				dialer := tls.Dialer{NetDialer: &net.Dialer{}, Config: t.TLSClientConfig}
-				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+				conn, err := DialTLS(ctx, dial, network, a.String(), t.TLSClientConfig)
 				if err != nil {
 					lastErr = err
 					continue
 				}
 
-				tcpConn, err := tcp.ExtractConn(conn)
-				if err != nil {
-					return nil, err
-				}
-
-				err = tcp.SetTimeouts(tcpConn, 0)
-				if err != nil {
-					return nil, err
-				}
-
 				return conn, nil
 			}
 
@@ -176,6 +181,53 @@ func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, pr
 	return client, nil
 }
 
+// DialTLS establishes a TLS connection to the address with the given dial function, or a net.Dialer if it is nil.
+// TCP timeouts are set on the underlying connection, if it is a TCP connection.
+func DialTLS(ctx context.Context, dial DialFunc, network string, address string, config *tls.Config) (*tls.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	conn, err := dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if ok {
+		err = tcp.SetTimeouts(tcpConn, 0)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	// Verify the server by the host it was dialed by, as tls.Dial does, unless a name is configured.
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
 // SetClusterNotification sets the client's proxy to apply the forwarding headers to a request.
 func (c *Client) SetClusterNotification() {
 	c.Transport.(*http.Transport).Proxy = forwardingProxy
@@ -197,6 +249,9 @@ func forwardingProxy(r *http.Request) (*url.URL, error) {
 
 	r.Header.Add(request.HeaderForwardedAddress, r.RemoteAddr)
 
+	// Carry the trace context of the request along with the notification.
+	tracing.Inject(ctx, r.Header)
+
 	return shared.ProxyFromEnvironment(r)
 }
 
@@ -258,17 +313,35 @@ func (c *Client) rawQuery(ctx context.Context, method string, url *api.URL, data
 		}
 	}
 
+	// Propagate the ID of the request being handled, if any, so that it can be traced across the cluster.
+	requestID := logging.RequestID(ctx)
+	if requestID != "" {
+		req.Header.Set(logging.HeaderRequestID, requestID)
+	}
+
 	return c.MakeRequest(req)
 }
 
 // MakeRequest performs a request and parses the response into an api.Response.
-func (c *Client) MakeRequest(r *http.Request) (*api.Response, error) {
+// The request is traced, and its trace context is sent along with it so that the receiving member can continue the trace.
+func (c *Client) MakeRequest(r *http.Request) (_ *api.Response, err error) {
+	ctx, span := tracing.Start(r.Context(), r.Method+" "+r.URL.Path, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.method", r.Method),
+		attribute.String("server.address", r.URL.Host),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	r = r.WithContext(ctx)
+	tracing.Inject(ctx, r.Header)
+
 	// Send the request
 	resp, err := c.Do(r)
 	if err != nil {
 		return nil, err
 	}
 
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
 	parsedResponse, err := parseResponse(resp)
 	if err != nil {
 		return nil, err
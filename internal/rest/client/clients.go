@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// AddClientCertificate asks the cluster to trust the given client certificate, in exchange for a join token.
+func (c *Client) AddClientCertificate(ctx context.Context, args types.ClientCertificatePost) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", PublicEndpoint, api.NewURL().Path("clients"), args, nil)
+}
+
+// GetClientCertificates returns the clients trusted by the cluster.
+func (c *Client) GetClientCertificates(ctx context.Context) ([]types.ClientCertificate, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	clients := []types.ClientCertificate{}
+	err := c.QueryStruct(queryCtx, "GET", PublicEndpoint, api.NewURL().Path("clients"), nil, &clients)
+
+	return clients, err
+}
+
+// DeleteClientCertificate revokes the trust of the client with the given name.
+func (c *Client) DeleteClientCertificate(ctx context.Context, name string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", PublicEndpoint, api.NewURL().Path("clients", name), nil, nil)
+}
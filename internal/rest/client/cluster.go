@@ -48,6 +48,14 @@ func (c *Client) DeleteClusterMember(ctx context.Context, name string, force boo
 	return c.QueryStruct(queryCtx, "DELETE", PublicEndpoint, endpoint, nil, nil)
 }
 
+// AbortClusterMemberJoin removes the pending cluster member with the given name, aborting its join.
+func (c *Client) AbortClusterMemberJoin(ctx context.Context, name string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", PublicEndpoint, api.NewURL().Path("cluster", name, "join"), nil, nil)
+}
+
 // ResetClusterMember clears the state directory of the cluster member, and re-execs its daemon.
 func (c *Client) ResetClusterMember(ctx context.Context, name string, force bool) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
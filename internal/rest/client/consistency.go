@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// CheckConsistency compares the truststore, the database, and dqlite on the cluster member and returns any issues.
+func (c *Client) CheckConsistency(ctx context.Context) (*types.ConsistencyReport, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	report := types.ConsistencyReport{}
+	err := c.QueryStruct(queryCtx, "GET", InternalEndpoint, api.NewURL().Path("consistency"), nil, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// RepairConsistency repairs the given consistency issues, and returns the issues that remain.
+func (c *Client) RepairConsistency(ctx context.Context, issues []types.ConsistencyIssue) (*types.ConsistencyReport, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	report := types.ConsistencyReport{}
+	err := c.QueryStruct(queryCtx, "POST", InternalEndpoint, api.NewURL().Path("consistency"), types.ConsistencyRepair{Issues: issues}, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
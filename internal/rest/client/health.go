@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// GetHealth returns the health of each component of the daemon.
+// The report is returned even if the daemon considers itself unhealthy.
+func (c *Client) GetHealth(ctx context.Context) (*types.Health, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	health := types.Health{}
+	err := c.QueryStruct(queryCtx, "GET", PublicEndpoint, api.NewURL().Path("health"), nil, &health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
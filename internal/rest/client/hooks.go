@@ -32,3 +32,14 @@ func RunNewMemberHook(ctx context.Context, c *Client, config types.HookNewMember
 
 	return c.QueryStruct(queryCtx, "POST", InternalEndpoint, api.NewURL().Path("hooks", string(types.OnNewMember)), config, nil)
 }
+
+// GetHookRuns returns the most recent runs of hooks on the cluster member, oldest first.
+func (c *Client) GetHookRuns(ctx context.Context) ([]types.HookRun, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	runs := []types.HookRun{}
+	err := c.QueryStruct(queryCtx, "GET", PublicEndpoint, api.NewURL().Path("hooks"), nil, &runs)
+
+	return runs, err
+}
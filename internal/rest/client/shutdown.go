@@ -12,5 +12,11 @@ func (c *Client) ShutdownDaemon(ctx context.Context) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	return c.QueryStruct(queryCtx, "POST", ControlEndpoint, api.NewURL().Path("shutdown"), nil, nil)
+	// Daemons are shut down over the control socket, or the public API for remote clients.
+	endpoint := ControlEndpoint
+	if c.url.URL.Scheme == "https" {
+		endpoint = PublicEndpoint
+	}
+
+	return c.QueryStruct(queryCtx, "POST", endpoint, api.NewURL().Path("shutdown"), nil, nil)
 }
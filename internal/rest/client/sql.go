@@ -31,7 +31,13 @@ func (c *Client) GetSQL(ctx context.Context, schema bool) (*types.SQLDump, error
 
 // PostSQL executes a SQL query against the database.
 func (c *Client) PostSQL(ctx context.Context, query types.SQLQuery) (*types.SQLBatch, error) {
-	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	// Allow for the query's own timeout, with some leeway for the response to arrive.
+	timeout := 30 * time.Second
+	if query.Timeout > 0 {
+		timeout = time.Duration(query.Timeout)*time.Second + 5*time.Second
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	batch := &types.SQLBatch{}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// GetTasks returns the status of the background tasks registered on the cluster member.
+func (c *Client) GetTasks(ctx context.Context) ([]types.Task, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tasks := []types.Task{}
+	err := c.QueryStruct(queryCtx, "GET", PublicEndpoint, api.NewURL().Path("tasks"), nil, &tasks)
+
+	return tasks, err
+}
+
+// TriggerTask runs the named background task as soon as possible.
+func (c *Client) TriggerTask(ctx context.Context, name string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", PublicEndpoint, api.NewURL().Path("tasks", name), nil, nil)
+}
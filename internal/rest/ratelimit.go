@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/canonical/microcluster/rest"
+)
+
+// rateLimiter enforces a rest.RateLimit with a token bucket for each source address, and a semaphore for concurrency.
+type rateLimiter struct {
+	rate     float64 // Tokens added to each bucket per second.
+	burst    float64 // Capacity of each bucket.
+	interval time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	concurrent chan struct{}
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns a rateLimiter enforcing the given limit, or nil if the limit is nil.
+func newRateLimiter(limit *rest.RateLimit) *rateLimiter {
+	if limit == nil {
+		return nil
+	}
+
+	l := &rateLimiter{buckets: map[string]*tokenBucket{}}
+	if limit.Requests > 0 {
+		l.interval = limit.Interval
+		if l.interval <= 0 {
+			l.interval = time.Second
+		}
+
+		l.burst = float64(limit.Burst)
+		if limit.Burst <= 0 {
+			l.burst = float64(limit.Requests)
+		}
+
+		l.rate = float64(limit.Requests) / l.interval.Seconds()
+	}
+
+	if limit.MaxConcurrent > 0 {
+		l.concurrent = make(chan struct{}, limit.MaxConcurrent)
+	}
+
+	return l
+}
+
+// allow takes a token from the bucket of the given source address.
+// If the bucket is empty, it returns false and how long the source should wait before retrying.
+func (l *rateLimiter) allow(remoteAddr string, now time.Time) (bool, time.Duration) {
+	if l.rate == 0 {
+		return true, 0
+	}
+
+	source, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		source = remoteAddr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget about sources whose buckets would have refilled by now, so the map does not grow without bound.
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) > refill {
+		for addr, bucket := range l.buckets {
+			if now.Sub(bucket.updated) > refill {
+				delete(l.buckets, addr)
+			}
+		}
+
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[source]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[source] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+
+	bucket.tokens--
+
+	return true, 0
+}
+
+// acquire reserves a slot for a concurrent request. If none is available, it returns false.
+// Otherwise the returned function must be called once the request has completed.
+func (l *rateLimiter) acquire() (release func(), ok bool) {
+	if l.concurrent == nil {
+		return func() {}, true
+	}
+
+	select {
+	case l.concurrent <- struct{}{}:
+		return func() { <-l.concurrent }, true
+	default:
+		return nil, false
+	}
+}
+
+// retryAfterSeconds rounds the wait up to a whole number of seconds, of at least one, for the Retry-After header.
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/rest"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newRateLimiter(&rest.RateLimit{Requests: 2, Interval: time.Second, Burst: 3})
+	now := time.Now()
+
+	// The burst is available straight away.
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.allow("10.0.0.1:4000", now)
+		require.True(t, ok)
+	}
+
+	ok, wait := limiter.allow("10.0.0.1:4001", now)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+	require.Equal(t, 1, retryAfterSeconds(wait))
+
+	// Other sources have their own bucket.
+	ok, _ = limiter.allow("10.0.0.2:4000", now)
+	require.True(t, ok)
+
+	// Tokens are replenished over time.
+	ok, _ = limiter.allow("10.0.0.1:4000", now.Add(500*time.Millisecond))
+	require.True(t, ok)
+
+	// Idle sources are forgotten once their bucket would be full again.
+	limiter.allow("10.0.0.3:4000", now.Add(time.Minute))
+	require.Len(t, limiter.buckets, 1)
+}
+
+func TestRateLimiterAcquire(t *testing.T) {
+	limiter := newRateLimiter(&rest.RateLimit{MaxConcurrent: 1})
+
+	ok, _ := limiter.allow("10.0.0.1:4000", time.Now())
+	require.True(t, ok)
+
+	release, ok := limiter.acquire()
+	require.True(t, ok)
+
+	_, ok = limiter.acquire()
+	require.False(t, ok)
+
+	release()
+
+	_, ok = limiter.acquire()
+	require.True(t, ok)
+
+	require.Nil(t, newRateLimiter(nil))
+}
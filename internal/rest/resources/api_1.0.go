@@ -13,19 +13,20 @@ import (
 
 var api10Cmd = rest.Endpoint{
 	AllowedBeforeInit: true,
+	RateLimit:         untrustedRateLimit,
 
 	Get: rest.EndpointAction{Handler: api10Get, AllowUntrusted: true},
 }
 
 func api10Get(s *state.State, r *http.Request) response.Response {
-	addrPort, err := types.ParseAddrPort(s.Address().URL.Host)
+	hostPort, err := types.ParseHostPort(s.Address().URL.Host)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	return response.SyncResponse(true, internalTypes.Server{
 		Name:    s.Name(),
-		Address: addrPort,
+		Address: hostPort,
 		Ready:   s.Database.IsOpen(),
 	})
 }
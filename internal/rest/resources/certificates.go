@@ -12,6 +12,7 @@ import (
 	"github.com/canonical/lxd/lxd/response"
 
 	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/internal/logging"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
@@ -22,7 +23,7 @@ var clusterCertificatesCmd = rest.Endpoint{
 	AllowedBeforeInit: true,
 	Path:              "cluster/certificates",
 
-	Put: rest.EndpointAction{Handler: clusterCertificatesPut, AccessHandler: access.AllowAuthenticated},
+	Put: rest.EndpointAction{Handler: clusterCertificatesPut, AccessHandler: access.AllowMember},
 }
 
 func clusterCertificatesPut(s *state.State, r *http.Request) response.Response {
@@ -41,7 +42,7 @@ func clusterCertificatesPut(s *state.State, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		err = cluster.Query(s.Context, true, func(ctx context.Context, c *client.Client) error {
+		err = cluster.Query(logging.Inherit(s.Context, r.Context()), true, func(ctx context.Context, c *client.Client) error {
 			return c.UpdateClusterCertificate(ctx, req)
 		})
 		if err != nil {
@@ -84,7 +85,7 @@ func clusterCertificatesPut(s *state.State, r *http.Request) response.Response {
 	}
 
 	// Load the new cluster cert from the state directory on this node.
-	err = state.ReloadClusterCert()
+	err = s.ReloadClusterCert()
 	if err != nil {
 		return response.SmartError(err)
 	}
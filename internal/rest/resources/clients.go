@@ -0,0 +1,150 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/validate"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var clientsCmd = rest.Endpoint{
+	Path: "clients",
+
+	RateLimit: untrustedRateLimit,
+
+	Post: rest.EndpointAction{Handler: clientsPost, AllowUntrusted: true},
+	Get:  rest.EndpointAction{Handler: clientsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var clientCmd = rest.Endpoint{
+	Path: "clients/{name}",
+
+	Delete: rest.EndpointAction{Handler: clientDelete, AccessHandler: access.AllowPrivileged},
+}
+
+// clientsPost trusts the certificate of a client outside of the cluster, in exchange for a join token issued under the
+// client's name. The token is consumed, and failed attempts are counted in the same way as for joining members.
+func clientsPost(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.ClientCertificatePost{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = validate.IsHostname(req.Name)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid client name %q: %w", req.Name, err))
+	}
+
+	if req.Certificate.Certificate == nil {
+		return response.BadRequest(fmt.Errorf("Missing client certificate"))
+	}
+
+	resp := checkJoinToken(s, r, req.Name, req.Secret)
+	if resp != response.EmptySyncResponse {
+		return resp
+	}
+
+	fingerprint := shared.CertFingerprint(req.Certificate.Certificate)
+	if s.Remotes().RemoteByCertificateFingerprint(fingerprint) != nil {
+		return response.BadRequest(fmt.Errorf("Certificate belongs to a cluster member"))
+	}
+
+	err = s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := cluster.GetInternalTokenRecords(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		record := cluster.MatchInternalTokenRecord(records, req.Secret)
+		if record == nil || record.Name != req.Name {
+			return errJoinRejected
+		}
+
+		existing, err := cluster.GetInternalClientCertificates(ctx, tx, cluster.InternalClientCertificateFilter{Fingerprint: &fingerprint})
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 {
+			return fmt.Errorf("Certificate is already trusted as client %q", existing[0].Name)
+		}
+
+		_, err = cluster.CreateInternalClientCertificate(ctx, tx, cluster.InternalClientCertificate{
+			Name:        req.Name,
+			Fingerprint: fingerprint,
+			Certificate: req.Certificate.String(),
+			CreatedAt:   s.Clock.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return cluster.DeleteInternalTokenRecord(ctx, tx, record.Name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s.Clients.Invalidate()
+	logging.FromContext(r.Context()).Info("Trusted new client", logger.Ctx{"name": req.Name, "fingerprint": fingerprint})
+
+	return response.EmptySyncResponse
+}
+
+func clientsGet(s *state.State, r *http.Request) response.Response {
+	var clients []internalTypes.ClientCertificate
+	err := s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+		certs, err := cluster.GetInternalClientCertificates(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		clients = make([]internalTypes.ClientCertificate, 0, len(certs))
+		for _, cert := range certs {
+			clients = append(clients, cert.ToAPI())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, clients)
+}
+
+func clientDelete(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteInternalClientCertificate(ctx, tx, name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s.Clients.Invalidate()
+
+	return response.EmptySyncResponse
+}
@@ -9,8 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"strings"
-	"sync"
 	"time"
 
 	dqliteClient "github.com/canonical/go-dqlite/client"
@@ -19,10 +17,10 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/validate"
 	"github.com/gorilla/mux"
-	"golang.org/x/sys/unix"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
@@ -35,6 +33,10 @@ import (
 var clusterCmd = rest.Endpoint{
 	Path: "cluster",
 
+	// Join requests are untrusted, but cluster members also list the cluster members on the leader before
+	// notifying them, so the limit must allow for bursts of requests from the same member.
+	RateLimit: &rest.RateLimit{Requests: 60, Interval: time.Minute, Burst: 20, MaxConcurrent: 10},
+
 	Post: rest.EndpointAction{Handler: clusterPost, AllowUntrusted: true},
 	Get:  rest.EndpointAction{Handler: clusterGet, AccessHandler: access.AllowAuthenticated},
 }
@@ -42,8 +44,8 @@ var clusterCmd = rest.Endpoint{
 var clusterMemberCmd = rest.Endpoint{
 	Path: "cluster/{name}",
 
-	Put:    rest.EndpointAction{Handler: clusterMemberPut, AccessHandler: access.AllowAuthenticated},
-	Delete: rest.EndpointAction{Handler: clusterMemberDelete, AccessHandler: access.AllowAuthenticated},
+	Put:    rest.EndpointAction{Handler: clusterMemberPut, AccessHandler: access.AllowMember},
+	Delete: rest.EndpointAction{Handler: clusterMemberDelete, AccessHandler: access.AllowPrivileged},
 }
 
 func clusterPost(s *state.State, r *http.Request) response.Response {
@@ -58,12 +60,7 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 	ctx, cancel := context.WithTimeout(s.Context, time.Second*30)
 	defer cancel()
 
-	leaderClient, err := s.Database.Leader(ctx)
-	if err != nil {
-		return response.SmartError(err)
-	}
-
-	leaderInfo, err := leaderClient.Leader(ctx)
+	isLeader, err := s.Database.CheckLeader(ctx)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -73,14 +70,22 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(fmt.Errorf("Invalid cluster member name %q: %w", req.Name, err))
 	}
 
-	// Check if any of the remote's addresses are currently in use.
-	existingRemote := s.Remotes().RemoteByAddress(req.Address)
+	// Join requests forwarded by cluster members have already had their token checked by the member that received them.
+	if access.AllowAuthenticated(s, r) != response.EmptySyncResponse {
+		resp := checkJoinToken(s, r, req.Name, req.Secret)
+		if resp != response.EmptySyncResponse {
+			return resp
+		}
+	}
+
+	// Check if the remote's address is currently in use. The address comes from the joiner, so it is not resolved.
+	existingRemote := s.Remotes().RemoteByExactAddress(req.Address)
 	if existingRemote != nil {
 		return response.SmartError(fmt.Errorf("Remote with address %q exists", req.Address.String()))
 	}
 
 	// Forward request to leader.
-	if leaderInfo.Address != s.Address().URL.Host {
+	if !isLeader {
 		client, err := s.Leader()
 		if err != nil {
 			return response.SmartError(err)
@@ -110,13 +115,19 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 			APIExtensions:  req.Extensions,
 			Heartbeat:      time.Time{},
 			Role:           cluster.Pending,
+			CreatedAt:      s.Clock.Now(),
 		}
 
-		record, err := cluster.GetInternalTokenRecord(ctx, tx, req.Secret)
+		records, err := cluster.GetInternalTokenRecords(ctx, tx)
 		if err != nil {
 			return err
 		}
 
+		record := cluster.MatchInternalTokenRecord(records, req.Secret)
+		if record == nil {
+			return errJoinRejected
+		}
+
 		_, err = cluster.CreateInternalClusterMember(ctx, tx, dbClusterMember)
 		if err != nil {
 			return err
@@ -200,7 +211,7 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 	// Send a small request to each node to ensure they are reachable.
 	for i, clusterMember := range apiClusterMembers {
 		addr := api.NewURL().Scheme("https").Host(clusterMember.Address.String())
-		d, err := internalClient.New(*addr, s.ServerCert(), clusterCert, false)
+		d, err := internalClient.NewWithDialer(*addr, s.ServerCert(), clusterCert, false, s.Dial)
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", addr.String(), err))
 		}
@@ -209,18 +220,13 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 		if err == nil {
 			apiClusterMembers[i].Status = internalTypes.MemberOnline
 		} else {
-			logger.Warnf("Failed to get status of cluster member with address %q: %v", addr.String(), err)
+			logging.FromContext(r.Context()).Warn("Failed to get status of cluster member", logger.Ctx{"address": addr.String(), "error": err})
 		}
 	}
 
 	return response.SyncResponse(true, apiClusterMembers)
 }
 
-// clusterDisableMu is used to prevent the daemon process from being replaced/stopped during removal from the
-// cluster until such time as the request that initiated the removal has finished. This allows for self removal
-// from the cluster when not the leader.
-var clusterDisableMu sync.Mutex
-
 func clusterMemberPut(s *state.State, r *http.Request) response.Response {
 	force := r.URL.Query().Get("force") == "1"
 	reExec, err := resetClusterMember(r.Context(), s, force)
@@ -255,7 +261,7 @@ func resetClusterMember(ctx context.Context, s *state.State, force bool) (reExec
 		return nil, fmt.Errorf("Failed shutting down database: %w", err)
 	}
 
-	err = state.StopListeners()
+	err = s.StopListeners()
 	if err != nil && !force {
 		return nil, fmt.Errorf("Failed shutting down listeners: %w", err)
 	}
@@ -270,21 +276,11 @@ func resetClusterMember(ctx context.Context, s *state.State, force bool) (reExec
 
 		// Wait until we can acquire the lock. This way if another request is holding the lock we won't
 		// replace/stop the LXD daemon until that request has finished.
-		clusterDisableMu.Lock()
-		defer clusterDisableMu.Unlock()
-		execPath, err := os.Readlink("/proc/self/exe")
-		if err != nil {
-			execPath = "bad-exec-path"
-		}
+		s.ClusterDisableMu.Lock()
+		defer s.ClusterDisableMu.Unlock()
 
-		// The execPath from /proc/self/exe can end with " (deleted)" if the lxd binary has been removed/changed
-		// since the lxd process was started, strip this so that we only return a valid path.
-		logger.Info("Restarting daemon following removal from cluster")
-		execPath = strings.TrimSuffix(execPath, " (deleted)")
-		err = unix.Exec(execPath, os.Args, os.Environ())
-		if err != nil {
-			logger.Error("Failed restarting daemon", logger.Ctx{"err": err})
-		}
+		logging.FromContext(ctx).Info("Restarting daemon following removal from cluster")
+		s.ReExec()
 	}
 
 	return reExec, nil
@@ -304,7 +300,8 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(fmt.Errorf("No remote exists with the given name %q", name))
 	}
 
-	ctx, cancel := context.WithTimeout(s.Context, time.Second*30)
+	log := logging.FromContext(r.Context())
+	ctx, cancel := context.WithTimeout(logging.Inherit(s.Context, r.Context()), time.Second*30)
 	defer cancel()
 
 	leader, err := s.Database.Leader(ctx)
@@ -324,14 +321,14 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 			// clusterPutDisableMu before we forward the request to the leader, so that when the leader
 			// goes on to request clusterPutDisable back to ourselves it won't be actioned until we
 			// have returned this request back to the original client.
-			clusterDisableMu.Lock()
-			logger.Info("Acquired cluster self removal lock", logger.Ctx{"member": name})
+			s.ClusterDisableMu.Lock()
+			log.Info("Acquired cluster self removal lock", logger.Ctx{"member": name})
 
 			go func() {
 				<-r.Context().Done() // Wait until request is finished.
 
-				logger.Info("Releasing cluster self removal lock", logger.Ctx{"member": name})
-				clusterDisableMu.Unlock()
+				log.Info("Releasing cluster self removal lock", logger.Ctx{"member": name})
+				s.ClusterDisableMu.Unlock()
 			}()
 		}
 
@@ -377,7 +374,7 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 
 	// If we can't find the node in dqlite, that means it failed to fully initialize. It still might have a record in our database so continue along anyway.
 	if index < 0 {
-		logger.Errorf("No dqlite record exists for %q, deleting from internal record instead", remote.Name)
+		log.Error("No dqlite record exists for cluster member, deleting from internal record instead", logger.Ctx{"member": remote.Name})
 	}
 
 	var clusterMembers []cluster.InternalClusterMember
@@ -448,14 +445,14 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		clusterDisableMu.Lock()
-		logger.Info("Acquired cluster self removal lock", logger.Ctx{"member": name})
+		s.ClusterDisableMu.Lock()
+		log.Info("Acquired cluster self removal lock", logger.Ctx{"member": name})
 
 		go func() {
 			<-r.Context().Done() // Wait until request is finished.
 
-			logger.Info("Releasing cluster self removal lock", logger.Ctx{"member": name})
-			clusterDisableMu.Unlock()
+			log.Info("Releasing cluster self removal lock", logger.Ctx{"member": name})
+			s.ClusterDisableMu.Unlock()
 		}()
 
 		err = client.DeleteClusterMember(s.Context, name, force)
@@ -486,13 +483,19 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 	}
 
 	// Set the forwarded flag so that the the system to be removed knows the removal is in progress.
-	c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, true)
+	c, err := internalClient.NewWithDialer(remote.URL(), s.ServerCert(), publicKey, true, s.Dial)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	hookOptions := internalTypes.HookRemoveMemberOptions{
+		Force:     force,
+		Member:    &internalTypes.ClusterMemberLocal{Name: remote.Name, Address: remote.Address, Certificate: remote.Certificate},
+		Initiator: s.Name(),
+	}
+
 	// Tell the cluster member to run its PreRemove hook and return.
-	err = internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), internalTypes.HookRemoveMemberOptions{Force: force})
+	err = internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), hookOptions)
 	if err != nil && !force {
 		return response.SmartError(err)
 	}
@@ -523,7 +526,7 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	c, err = internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+	c, err = internalClient.NewWithDialer(remote.URL(), s.ServerCert(), publicKey, false, s.Dial)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -539,26 +542,26 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 	}
 
 	// Run the PostRemove hook locally.
-	err = state.PostRemoveHook(s, force)
+	err = s.PostRemoveHook(s.ForRequest(r), state.HookContext{Member: *hookOptions.Member, Initiator: hookOptions.Initiator, Force: force})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	// Run the PostRemove hook on all other members.
 	remotes := s.Remotes()
-	err = cluster.Query(s.Context, true, func(ctx context.Context, c *client.Client) error {
+	err = cluster.Query(logging.Inherit(s.Context, r.Context()), true, func(ctx context.Context, c *client.Client) error {
 		c.SetClusterNotification()
-		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		hostPort, err := types.ParseHostPort(c.URL().URL.Host)
 		if err != nil {
 			return err
 		}
 
-		remote := remotes.RemoteByAddress(addrPort)
+		remote := remotes.RemoteByAddress(hostPort)
 		if remote == nil {
 			return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
 		}
 
-		return internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force})
+		return internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), hookOptions)
 	})
 	if err != nil {
 		return response.SmartError(err)
@@ -0,0 +1,305 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+var consistencyCmd = rest.Endpoint{
+	Path: "consistency",
+
+	Get:  rest.EndpointAction{Handler: consistencyGet, AccessHandler: access.AllowMember},
+	Post: rest.EndpointAction{Handler: consistencyPost, AccessHandler: access.AllowMember},
+}
+
+// consistencyGet reports any disagreement between the local truststore, the database, and dqlite.
+func consistencyGet(s *state.State, r *http.Request) response.Response {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := checkConsistency(ctx, s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, report)
+}
+
+// consistencyPost repairs the given consistency issues, if they are still present.
+// Issues with the local truststore are repaired by replacing it with the database record of cluster members.
+// Issues with the database or dqlite are repaired by the leader, to which they are forwarded if necessary.
+func consistencyPost(s *state.State, r *http.Request) response.Response {
+	var req types.ConsistencyRepair
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if len(req.Issues) == 0 {
+		return response.BadRequest(fmt.Errorf("No issues to repair were given"))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := checkConsistency(ctx, s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only repair the requested issues that are still present, in case the cluster has changed in the meantime.
+	current := make(map[types.ConsistencyIssue]bool, len(report.Issues))
+	for _, issue := range report.Issues {
+		current[issue] = true
+	}
+
+	var localIssues, clusterIssues []types.ConsistencyIssue
+	for _, issue := range req.Issues {
+		if !current[issue] {
+			return response.PreconditionFailed(fmt.Errorf("Issue %q for %q is no longer present, re-run the consistency check", issue.Type, issue.Name))
+		}
+
+		if issue.Type.Local() {
+			localIssues = append(localIssues, issue)
+		} else {
+			clusterIssues = append(clusterIssues, issue)
+		}
+	}
+
+	if len(clusterIssues) > 0 {
+		err = repairClusterConsistency(ctx, s, clusterIssues)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	// Always refresh the truststore after repairing cluster issues, as database records may have been removed.
+	if len(localIssues) > 0 || len(clusterIssues) > 0 {
+		err = repairTruststore(ctx, s)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	report, err = checkConsistency(ctx, s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, report)
+}
+
+// checkConsistency compares the local truststore, the database record of cluster members, and the dqlite node list.
+func checkConsistency(ctx context.Context, s *state.State) (*types.ConsistencyReport, error) {
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer leader.Close()
+
+	nodes, err := s.Database.Cluster(ctx, leader)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []cluster.InternalClusterMember
+	err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		members, err = cluster.GetInternalClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ConsistencyReport{
+		Member: s.Name(),
+		Issues: diffClusterState(s.Remotes().RemotesByName(), members, nodes),
+	}, nil
+}
+
+// diffClusterState returns the issues found when comparing the given truststore remotes, database cluster members,
+// and dqlite nodes. Remotes are matched to cluster members by name, and dqlite nodes by address.
+func diffClusterState(remotes map[string]trust.Remote, members []cluster.InternalClusterMember, nodes []dqliteClient.NodeInfo) []types.ConsistencyIssue {
+	issues := []types.ConsistencyIssue{}
+
+	nodesByAddress := make(map[string]dqliteClient.NodeInfo, len(nodes))
+	for _, node := range nodes {
+		nodesByAddress[node.Address] = node
+	}
+
+	remainingRemotes := make(map[string]trust.Remote, len(remotes))
+	for name, remote := range remotes {
+		remainingRemotes[name] = remote
+	}
+
+	for _, member := range members {
+		_, hasNode := nodesByAddress[member.Address]
+		delete(nodesByAddress, member.Address)
+
+		if !hasNode {
+			issue := types.ConsistencyIssue{Type: types.ConsistencyMissingNode, Name: member.Name, Address: member.Address, Message: "Cluster member has no dqlite node"}
+			if member.Role == cluster.Pending {
+				issue.Type = types.ConsistencyPendingMember
+				issue.Message = "Pending cluster member has no dqlite node"
+			}
+
+			issues = append(issues, issue)
+		}
+
+		remote, ok := remainingRemotes[member.Name]
+		if !ok {
+			issues = append(issues, types.ConsistencyIssue{Type: types.ConsistencyMissingRemote, Name: member.Name, Address: member.Address, Message: "Cluster member is missing from the truststore"})
+			continue
+		}
+
+		delete(remainingRemotes, member.Name)
+
+		if remote.Address.String() != member.Address {
+			issues = append(issues, types.ConsistencyIssue{Type: types.ConsistencyRemoteMismatch, Name: member.Name, Address: member.Address, Message: fmt.Sprintf("Truststore has address %q", remote.Address.String())})
+			continue
+		}
+
+		cert, err := apiTypes.ParseX509Certificate(member.Certificate)
+		if err != nil || !cert.Equal(remote.Certificate.Certificate) {
+			issues = append(issues, types.ConsistencyIssue{Type: types.ConsistencyRemoteMismatch, Name: member.Name, Address: member.Address, Message: "Truststore has a different certificate"})
+		}
+	}
+
+	for name, remote := range remainingRemotes {
+		issues = append(issues, types.ConsistencyIssue{Type: types.ConsistencyOrphanedRemote, Name: name, Address: remote.Address.String(), Message: "Truststore entry has no cluster member"})
+	}
+
+	for address := range nodesByAddress {
+		issues = append(issues, types.ConsistencyIssue{Type: types.ConsistencyOrphanedNode, Address: address, Message: "dqlite node has no cluster member"})
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Type != issues[j].Type {
+			return issues[i].Type < issues[j].Type
+		}
+
+		if issues[i].Name != issues[j].Name {
+			return issues[i].Name < issues[j].Name
+		}
+
+		return issues[i].Address < issues[j].Address
+	})
+
+	return issues
+}
+
+// repairClusterConsistency removes orphaned dqlite nodes and database cluster members without a dqlite node.
+// If this member is not the leader, the repair is forwarded to the leader.
+func repairClusterConsistency(ctx context.Context, s *state.State, issues []types.ConsistencyIssue) error {
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer leader.Close()
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if leaderInfo.Address != s.Address().URL.Host {
+		client, err := s.Leader()
+		if err != nil {
+			return err
+		}
+
+		_, err = client.RepairConsistency(ctx, issues)
+
+		return err
+	}
+
+	nodes, err := s.Database.Cluster(ctx, leader)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		switch issue.Type {
+		case types.ConsistencyOrphanedNode:
+			if issue.Address == leaderInfo.Address {
+				return fmt.Errorf("Refusing to remove the dqlite leader %q", issue.Address)
+			}
+
+			for _, node := range nodes {
+				if node.Address != issue.Address {
+					continue
+				}
+
+				logging.FromContext(ctx).Warn("Removing orphaned dqlite node", logger.Ctx{"address": node.Address, "id": node.ID})
+				err = leader.Remove(ctx, node.ID)
+				if err != nil {
+					return fmt.Errorf("Failed to remove dqlite node %q: %w", node.Address, err)
+				}
+			}
+
+		case types.ConsistencyPendingMember, types.ConsistencyMissingNode:
+			logging.FromContext(ctx).Warn("Removing cluster member with no dqlite node", logger.Ctx{"name": issue.Name, "address": issue.Address})
+			err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				return cluster.DeleteInternalClusterMember(ctx, tx, issue.Address)
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to remove cluster member %q: %w", issue.Name, err)
+			}
+
+		default:
+			return fmt.Errorf("Unknown consistency issue type %q", issue.Type)
+		}
+	}
+
+	return nil
+}
+
+// repairTruststore replaces the local truststore with the database record of cluster members.
+func repairTruststore(ctx context.Context, s *state.State) error {
+	var members []types.ClusterMember
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		dbMembers, err := cluster.GetInternalClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		members = make([]types.ClusterMember, 0, len(dbMembers))
+		for _, dbMember := range dbMembers {
+			member, err := dbMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			members = append(members, *member)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Remotes().Replace(s.OS.TrustDir, members...)
+}
@@ -0,0 +1,103 @@
+package resources
+
+import (
+	"testing"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/trust"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+func TestDiffClusterState(t *testing.T) {
+	newCert := func() (string, apiTypes.X509Certificate) {
+		certPEM, _, err := shared.GenerateMemCert(false, false)
+		require.NoError(t, err)
+
+		cert, err := apiTypes.ParseX509Certificate(string(certPEM))
+		require.NoError(t, err)
+
+		return string(certPEM), *cert
+	}
+
+	newRemote := func(name string, address string, cert apiTypes.X509Certificate) trust.Remote {
+		hostPort, err := apiTypes.ParseHostPort(address)
+		require.NoError(t, err)
+
+		return trust.Remote{Location: trust.Location{Name: name, Address: hostPort}, Certificate: cert}
+	}
+
+	pem0, cert0 := newCert()
+	pem1, cert1 := newCert()
+	_, cert2 := newCert()
+
+	tests := []struct {
+		name    string
+		remotes map[string]trust.Remote
+		members []cluster.InternalClusterMember
+		nodes   []dqliteClient.NodeInfo
+		issues  []types.ConsistencyIssueType
+	}{
+		{
+			name:    "Consistent",
+			remotes: map[string]trust.Remote{"n0": newRemote("n0", "10.0.0.1:9000", cert0)},
+			members: []cluster.InternalClusterMember{{Name: "n0", Address: "10.0.0.1:9000", Certificate: pem0, Role: "voter"}},
+			nodes:   []dqliteClient.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}},
+			issues:  []types.ConsistencyIssueType{},
+		},
+		{
+			name: "Pending member without dqlite node",
+			remotes: map[string]trust.Remote{
+				"n0": newRemote("n0", "10.0.0.1:9000", cert0),
+				"n1": newRemote("n1", "10.0.0.2:9000", cert1),
+			},
+			members: []cluster.InternalClusterMember{
+				{Name: "n0", Address: "10.0.0.1:9000", Certificate: pem0, Role: "voter"},
+				{Name: "n1", Address: "10.0.0.2:9000", Certificate: pem1, Role: cluster.Pending},
+			},
+			nodes:  []dqliteClient.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}},
+			issues: []types.ConsistencyIssueType{types.ConsistencyPendingMember},
+		},
+		{
+			name:    "Member without dqlite node and orphaned dqlite node",
+			remotes: map[string]trust.Remote{"n0": newRemote("n0", "10.0.0.1:9000", cert0)},
+			members: []cluster.InternalClusterMember{{Name: "n0", Address: "10.0.0.1:9000", Certificate: pem0, Role: "voter"}},
+			nodes:   []dqliteClient.NodeInfo{{ID: 2, Address: "10.0.0.3:9000"}},
+			issues:  []types.ConsistencyIssueType{types.ConsistencyMissingNode, types.ConsistencyOrphanedNode},
+		},
+		{
+			name: "Truststore drift",
+			remotes: map[string]trust.Remote{
+				"n0": newRemote("n0", "10.0.0.1:9000", cert2),
+				"n2": newRemote("n2", "10.0.0.3:9000", cert2),
+			},
+			members: []cluster.InternalClusterMember{
+				{Name: "n0", Address: "10.0.0.1:9000", Certificate: pem0, Role: "voter"},
+				{Name: "n1", Address: "10.0.0.2:9000", Certificate: pem1, Role: "voter"},
+			},
+			nodes: []dqliteClient.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.0.2:9000"}},
+			issues: []types.ConsistencyIssueType{
+				types.ConsistencyMissingRemote,
+				types.ConsistencyOrphanedRemote,
+				types.ConsistencyRemoteMismatch,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issues := diffClusterState(test.remotes, test.members, test.nodes)
+
+			issueTypes := make([]types.ConsistencyIssueType, 0, len(issues))
+			for _, issue := range issues {
+				issueTypes = append(issueTypes, issue.Type)
+			}
+
+			require.Equal(t, test.issues, issueTypes)
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/validate"
 
+	"github.com/canonical/microcluster/internal/logging"
 	"github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
@@ -26,7 +27,7 @@ import (
 var controlCmd = rest.Endpoint{
 	AllowedBeforeInit: true,
 
-	Post: rest.EndpointAction{Handler: controlPost, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: controlPost, AccessHandler: access.AllowPrivileged},
 }
 
 func controlPost(state *state.State, r *http.Request) response.Response {
@@ -107,7 +108,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 			return response.SmartError(fmt.Errorf("Cluster certificate token does not match that of cluster member %q", url.URL.Host))
 		}
 
-		d, err := client.New(*url, state.ServerCert(), cert, false)
+		d, err := client.NewWithDialer(*url, state.ServerCert(), cert, false, state.Dial)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -117,7 +118,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 			break
 		}
 
-		logger.Error("Unable to complete cluster join request", logger.Ctx{"address": addr.String(), "error": err})
+		logging.FromContext(r.Context()).Error("Unable to complete cluster join request", logger.Ctx{"address": addr.String(), "error": err})
 		lastErr = err
 	}
 
@@ -137,7 +138,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 			return
 		}
 
-		client, err := client.New(*url, state.ServerCert(), cert, false)
+		client, err := client.NewWithDialer(*url, state.ServerCert(), cert, false, state.Dial)
 		if err != nil {
 			return
 		}
@@ -153,7 +154,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 		// Use `force=1` to ensure the node is fully removed, in case its listener hasn't been set up.
 		err = client.DeleteClusterMember(context.Background(), req.Name, true)
 		if err != nil {
-			logger.Error("Failed to clean up cluster state after join failure", logger.Ctx{"error": err})
+			logging.FromContext(r.Context()).Error("Failed to clean up cluster state after join failure", logger.Ctx{"error": err})
 		}
 	})
 
@@ -162,7 +163,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 		return response.SmartError(err)
 	}
 
-	joinAddrs := types.AddrPorts{}
+	joinAddrs := types.HostPorts{}
 	clusterMembers := make([]trust.Remote, 0, len(joinInfo.ClusterMembers))
 	for _, clusterMember := range joinInfo.ClusterMembers {
 		remote := trust.Remote{
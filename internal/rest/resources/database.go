@@ -15,6 +15,9 @@ var databaseCmd = rest.Endpoint{
 	AllowedBeforeInit: true,
 	Path:              "database",
 
+	// Keep accepting dqlite connections while draining, in case this member is still the leader.
+	AllowedDuringShutdown: true,
+
 	Post:  rest.EndpointAction{Handler: databasePost},
 	Patch: rest.EndpointAction{Handler: databasePatch},
 }
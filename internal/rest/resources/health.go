@@ -0,0 +1,257 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/cluster"
+	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+// healthLeaderLatency is the dqlite leader round-trip time above which the leader is reported as degraded.
+const healthLeaderLatency = time.Second
+
+// healthHeartbeatAge is the time since the last successful heartbeat after which the heartbeat is reported as degraded.
+const healthHeartbeatAge = time.Second * internalClient.HeartbeatTimeout * 4
+
+// healthCertExpiry is the time before a certificate expires from which it is reported as degraded.
+const healthCertExpiry = 30 * 24 * time.Hour
+
+var healthCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "health",
+
+	Get: rest.EndpointAction{Handler: healthGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var healthLiveCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "health/live",
+	RateLimit:         untrustedRateLimit,
+
+	Get: rest.EndpointAction{Handler: healthLiveGet, AllowUntrusted: true},
+}
+
+// healthLiveGet reports that the daemon is running and able to serve requests, without any detail.
+// Requests received during shutdown are already rejected before reaching this handler.
+func healthLiveGet(s *state.State, r *http.Request) response.Response {
+	return response.EmptySyncResponse
+}
+
+// healthGet reports the health of each component of the daemon.
+// If any component is unhealthy, the report is returned with a 503 status code, so it can be used as a readiness probe.
+func healthGet(s *state.State, r *http.Request) response.Response {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	health := types.Health{Status: types.HealthStatusHealthy}
+	health.Components = append(health.Components, healthDatabase(s))
+
+	if s.Database.IsEmbedded() {
+		// An embedded database has no dqlite leader to reach, and no heartbeats.
+		health.Components = append(health.Components, healthTruststore(ctx, s))
+	} else if s.Database.IsOpen() {
+		health.Components = append(health.Components, healthLeader(ctx, s), healthHeartbeat(ctx, s), healthTruststore(ctx, s))
+	}
+
+	health.Components = append(health.Components, healthListeners(s), healthCertificates(s))
+
+	for _, check := range s.HealthChecks {
+		component := types.HealthComponent{Name: check.Name, Status: types.HealthStatusHealthy}
+		err := check.Check(ctx, s)
+		if err != nil {
+			component.Status = types.HealthStatusDegraded
+			if check.Critical {
+				component.Status = types.HealthStatusUnhealthy
+			}
+
+			component.Message = err.Error()
+		}
+
+		health.Components = append(health.Components, component)
+	}
+
+	for _, component := range health.Components {
+		health.Status = health.Status.Worse(component.Status)
+	}
+
+	if health.Status != types.HealthStatusUnhealthy {
+		return response.SyncResponse(true, health)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		return json.NewEncoder(w).Encode(api.ResponseRaw{
+			Type:       api.SyncResponse,
+			Status:     http.StatusText(http.StatusServiceUnavailable),
+			StatusCode: http.StatusServiceUnavailable,
+			Metadata:   health,
+		})
+	})
+}
+
+// healthDatabase reports whether the database is open.
+func healthDatabase(s *state.State) types.HealthComponent {
+	if !s.Database.IsOpen() {
+		return types.HealthComponent{Name: "database", Status: types.HealthStatusUnhealthy, Message: "Database is not open"}
+	}
+
+	return types.HealthComponent{Name: "database", Status: types.HealthStatusHealthy}
+}
+
+// healthLeader reports whether the dqlite leader is reachable, and how long it takes to respond.
+func healthLeader(ctx context.Context, s *state.State) types.HealthComponent {
+	component := types.HealthComponent{Name: "leader", Status: types.HealthStatusHealthy}
+
+	start := time.Now()
+	leaderClient, err := s.Database.Leader(ctx)
+	if err != nil {
+		component.Status = types.HealthStatusUnhealthy
+		component.Message = fmt.Sprintf("Failed to connect to dqlite leader: %v", err)
+
+		return component
+	}
+
+	defer leaderClient.Close()
+
+	leader, err := leaderClient.Leader(ctx)
+	if err != nil {
+		component.Status = types.HealthStatusUnhealthy
+		component.Message = fmt.Sprintf("Failed to get dqlite leader information: %v", err)
+
+		return component
+	}
+
+	latency := time.Since(start)
+	component.Details = map[string]string{"address": leader.Address, "latency": latency.String()}
+	if latency > healthLeaderLatency {
+		component.Status = types.HealthStatusDegraded
+		component.Message = fmt.Sprintf("dqlite leader took %v to respond", latency)
+	}
+
+	return component
+}
+
+// healthHeartbeat reports when the local cluster member last received a successful heartbeat.
+func healthHeartbeat(ctx context.Context, s *state.State) types.HealthComponent {
+	component := types.HealthComponent{Name: "heartbeat", Status: types.HealthStatusHealthy}
+
+	var member *cluster.InternalClusterMember
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		member, err = cluster.GetInternalClusterMember(ctx, tx, s.Name())
+
+		return err
+	})
+	if err != nil {
+		component.Status = types.HealthStatusUnhealthy
+		component.Message = fmt.Sprintf("Failed to get local cluster member record: %v", err)
+
+		return component
+	}
+
+	component.Details = map[string]string{"last_heartbeat": member.Heartbeat.Format(time.RFC3339)}
+	if member.Role == cluster.Pending {
+		component.Status = types.HealthStatusDegraded
+		component.Message = "Cluster member has not finished joining the cluster"
+	} else if s.Clock.Since(member.Heartbeat) > healthHeartbeatAge {
+		component.Status = types.HealthStatusDegraded
+		component.Message = fmt.Sprintf("No successful heartbeat in the last %v", healthHeartbeatAge)
+	}
+
+	return component
+}
+
+// healthTruststore reports whether the local truststore agrees with the database record of cluster members.
+func healthTruststore(ctx context.Context, s *state.State) types.HealthComponent {
+	component := types.HealthComponent{Name: "truststore", Status: types.HealthStatusHealthy}
+
+	report, err := checkConsistency(ctx, s)
+	if err != nil {
+		component.Status = types.HealthStatusUnhealthy
+		component.Message = fmt.Sprintf("Failed to check truststore consistency: %v", err)
+
+		return component
+	}
+
+	problems := []string{}
+	for _, issue := range report.Issues {
+		if issue.Type.Local() {
+			problems = append(problems, fmt.Sprintf("%s %q: %s", issue.Type, issue.Name, issue.Message))
+		}
+	}
+
+	if len(problems) > 0 {
+		component.Status = types.HealthStatusDegraded
+		component.Message = strings.Join(problems, ", ")
+	}
+
+	return component
+}
+
+// healthListeners reports whether each of the API listeners is serving.
+func healthListeners(s *state.State) types.HealthComponent {
+	component := types.HealthComponent{Name: "listeners", Status: types.HealthStatusHealthy, Details: map[string]string{}}
+	for endpointType, serving := range s.Endpoints.Status() {
+		if serving {
+			component.Details[endpointType.String()] = "serving"
+			continue
+		}
+
+		component.Details[endpointType.String()] = "stopped"
+		component.Status = types.HealthStatusUnhealthy
+		component.Message = "Not all listeners are serving"
+	}
+
+	return component
+}
+
+// healthCertificates reports when the server and cluster certificates expire.
+func healthCertificates(s *state.State) types.HealthComponent {
+	component := types.HealthComponent{Name: "certificates", Status: types.HealthStatusHealthy, Details: map[string]string{}}
+
+	certs := map[string]*shared.CertInfo{"server": s.ServerCert()}
+	if s.Database.IsOpen() {
+		certs["cluster"] = s.ClusterCert()
+	}
+
+	problems := []string{}
+	for name, certInfo := range certs {
+		cert, err := certInfo.PublicKeyX509()
+		if err != nil {
+			component.Status = types.HealthStatusUnhealthy
+			problems = append(problems, fmt.Sprintf("Failed to parse %s certificate: %v", name, err))
+			continue
+		}
+
+		component.Details[name] = cert.NotAfter.Format(time.RFC3339)
+
+		if s.Clock.Now().After(cert.NotAfter) {
+			component.Status = types.HealthStatusUnhealthy
+			problems = append(problems, fmt.Sprintf("The %s certificate has expired", name))
+		} else if s.Clock.Until(cert.NotAfter) < healthCertExpiry {
+			component.Status = component.Status.Worse(types.HealthStatusDegraded)
+			problems = append(problems, fmt.Sprintf("The %s certificate expires soon", name))
+		}
+	}
+
+	sort.Strings(problems)
+	component.Message = strings.Join(problems, ", ")
+
+	return component
+}
@@ -14,16 +14,21 @@ import (
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
 )
 
 var heartbeatCmd = rest.Endpoint{
 	Path: "heartbeat",
 
-	Post: rest.EndpointAction{Handler: heartbeatPost, AllowUntrusted: true},
+	// Heartbeats are only sent by the leader every few seconds. A new round is begun over the unix socket, which is not limited.
+	RateLimit: &rest.RateLimit{Requests: 1, Burst: 10, MaxConcurrent: 5},
+
+	Post: rest.EndpointAction{Handler: heartbeatPost, AccessHandler: access.AllowMember},
 }
 
 func heartbeatPost(s *state.State, r *http.Request) response.Response {
@@ -86,22 +91,23 @@ func heartbeatPost(s *state.State, r *http.Request) response.Response {
 // recently.
 func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	// Set a 5 second timeout in case dqlite locks up.
-	ctx, cancel := context.WithTimeout(s.Context, time.Second*30)
+	log := logging.FromContext(r.Context())
+	ctx, cancel := context.WithTimeout(logging.Inherit(s.Context, r.Context()), time.Second*30)
 	defer cancel()
 
 	// Only a leader can begin a heartbeat round.
-	leader, err := s.Database.Leader(ctx)
+	isLeader, err := s.Database.CheckLeader(ctx)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	leaderInfo, err := leader.Leader(ctx)
-	if err != nil {
-		return response.SmartError(err)
+	if !isLeader {
+		return response.SmartError(fmt.Errorf("Attempt to initiate heartbeat from non-leader"))
 	}
 
-	if s.Address().URL.Host != leaderInfo.Address {
-		return response.SmartError(fmt.Errorf("Attempt to initiate heartbeat from non-leader"))
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
 	// Get the database record of cluster members.
@@ -135,10 +141,13 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	}
 
 	if len(clusterMembers) == 0 || len(dqliteCluster) == 0 {
-		logger.Info("Skipping heartbeat as the cluster is still initializing")
+		log.Info("Skipping heartbeat as the cluster is still initializing")
 		return response.EmptySyncResponse
 	}
 
+	// Clean up any pending cluster members left behind by a failed join.
+	clusterMembers = removeStalePendingMembers(ctx, s, leader, clusterMembers, dqliteCluster)
+
 	dqliteMap := map[string]string{}
 	for _, member := range dqliteCluster {
 		dqliteMap[member.Address] = member.Role.String()
@@ -151,7 +160,7 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 
 		// If a cluster member is pending and dqlite does not have a record for it yet, then skip it this round.
 		if !ok && clusterMember.Role == string(cluster.Pending) {
-			logger.Debug("Skipping heartbeat for pending cluster member", logger.Ctx{"address": clusterMember.Address})
+			log.Debug("Skipping heartbeat for pending cluster member", logger.Ctx{"address": clusterMember.Address})
 			continue
 		}
 
@@ -163,10 +172,10 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	// then wait the up to half the request timeout before exiting to prevent sending more unsuccessful attempts.
 	leaderEntry := clusterMap[s.Address().URL.Host]
 	heartbeatInterval := time.Duration(time.Second * internalClient.HeartbeatTimeout * 2)
-	timeSinceLast := time.Since(leaderEntry.LastHeartbeat)
+	timeSinceLast := s.Clock.Since(leaderEntry.LastHeartbeat)
 	if timeSinceLast < heartbeatInterval {
 		sleepInterval := time.Duration(time.Second * internalClient.HeartbeatTimeout / 2)
-		timeUntilNext := time.Until(leaderEntry.LastHeartbeat.Add(heartbeatInterval))
+		timeUntilNext := s.Clock.Until(leaderEntry.LastHeartbeat.Add(heartbeatInterval))
 
 		// If we can send out a heartbeat sooner than the sleep timeout, sleep just long enough.
 		if timeUntilNext < sleepInterval {
@@ -178,13 +187,13 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 			sleepInterval = 2 * time.Second
 		}
 
-		logger.Debugf("Heartbeat was sent %v ago, sleep %v seconds before retrying", timeSinceLast, sleepInterval)
-		<-time.After(sleepInterval)
+		log.Debug("Heartbeat was sent recently, sleeping before retrying", logger.Ctx{"since": timeSinceLast, "sleep": sleepInterval})
+		<-s.Clock.After(sleepInterval)
 
 		return response.EmptySyncResponse
 	}
 
-	logger.Debug("Beginning new heartbeat round", logger.Ctx{"address": s.Address().URL.Host})
+	log.Debug("Beginning new heartbeat round", logger.Ctx{"address": s.Address().URL.Host})
 
 	// Update local record of cluster members from the database, including any pending nodes for authentication.
 	err = s.Remotes().Replace(s.OS.TrustDir, clusterMembers...)
@@ -193,7 +202,7 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	}
 
 	// Set the time of the last heartbeat to now.
-	leaderEntry.LastHeartbeat = time.Now()
+	leaderEntry.LastHeartbeat = s.Clock.Now()
 	clusterMap[s.Address().URL.Host] = leaderEntry
 
 	// Record the maximum schema version discovered.
@@ -217,30 +226,30 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	mapLock := sync.RWMutex{}
 	// Send heartbeat to non-leader members, updating their local member cache and updating the node.
 	// If we sent a heartbeat to this node within double the request timeout, then we can skip the node this round.
-	err = clusterClients.Query(s.Context, true, func(ctx context.Context, c *client.Client) error {
+	err = clusterClients.Query(logging.Inherit(s.Context, r.Context()), true, func(ctx context.Context, c *client.Client) error {
 		addr := c.URL().URL.Host
 
 		mapLock.RLock()
 		currentMember, ok := hbInfo.ClusterMembers[addr]
 		mapLock.RUnlock()
 		if !ok {
-			logger.Warnf("Skipping heartbeat cluster member record with address %v due to pending status", addr)
+			log.Warn("Skipping heartbeat for cluster member due to pending status", logger.Ctx{"address": addr})
 			return nil
 		}
 
-		timeSinceLast := time.Since(currentMember.LastHeartbeat)
+		timeSinceLast := s.Clock.Since(currentMember.LastHeartbeat)
 		if timeSinceLast < time.Duration(time.Second*internalClient.HeartbeatTimeout*2) {
-			logger.Warnf("Skipping heartbeat, one was sent %q ago", timeSinceLast.String())
+			log.Warn("Skipping heartbeat, one was sent recently", logger.Ctx{"address": addr, "since": timeSinceLast.String()})
 			return nil
 		}
 
 		err := c.Heartbeat(ctx, hbInfo)
 		if err != nil {
-			logger.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
+			log.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
 			return nil
 		}
 
-		currentMember.LastHeartbeat = time.Now()
+		currentMember.LastHeartbeat = s.Clock.Now()
 
 		mapLock.Lock()
 		hbInfo.ClusterMembers[addr] = currentMember
@@ -279,7 +288,18 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	err = state.OnHeartbeatHook(s)
+	if s.CheckConsistencyOnHeartbeat {
+		report, err := checkConsistency(ctx, s)
+		if err != nil {
+			log.Error("Failed to check cluster consistency", logger.Ctx{"error": err})
+		} else {
+			for _, issue := range report.Issues {
+				log.Warn("Found cluster consistency issue", logger.Ctx{"type": issue.Type, "name": issue.Name, "address": issue.Address, "message": issue.Message})
+			}
+		}
+	}
+
+	err = s.OnHeartbeatHook(s.ForRequest(r), state.HookContext{Initiator: s.Name(), Heartbeat: hbInfo.ClusterMembers})
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -18,7 +18,18 @@ import (
 var hooksCmd = rest.Endpoint{
 	Path: "hooks/{hookType}",
 
-	Post: rest.EndpointAction{Handler: hooksPost, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+	Post: rest.EndpointAction{Handler: hooksPost, AccessHandler: access.AllowMember, ProxyTarget: true},
+}
+
+var hookRunsCmd = rest.Endpoint{
+	Path: "hooks",
+
+	Get: rest.EndpointAction{Handler: hookRunsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// hookRunsGet returns the most recent runs of hooks on this cluster member, for debugging.
+func hookRunsGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.HookRuns())
 }
 
 func hooksPost(s *state.State, r *http.Request) response.Response {
@@ -35,7 +46,7 @@ func hooksPost(s *state.State, r *http.Request) response.Response {
 			return response.BadRequest(err)
 		}
 
-		err = state.PreRemoveHook(s, req.Force)
+		err = s.PreRemoveHook(s.ForRequest(r), removeHookContext(req))
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to execute pre-remove hook on cluster member %q: %w", s.Name(), err))
 		}
@@ -46,7 +57,7 @@ func hooksPost(s *state.State, r *http.Request) response.Response {
 			return response.BadRequest(err)
 		}
 
-		err = state.PostRemoveHook(s, req.Force)
+		err = s.PostRemoveHook(s.ForRequest(r), removeHookContext(req))
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to execute post-remove hook on cluster member %q: %w", s.Name(), err))
 		}
@@ -62,7 +73,19 @@ func hooksPost(s *state.State, r *http.Request) response.Response {
 			return response.SmartError(fmt.Errorf("No new member name given for NewMember hook execution"))
 		}
 
-		err = state.OnNewMemberHook(s)
+		// Members running an older version only send the name of the new member.
+		member := types.ClusterMemberLocal{Name: req.Name}
+		if req.Member != nil {
+			member = *req.Member
+		} else {
+			remote, ok := s.Remotes().RemotesByName()[req.Name]
+			if ok {
+				member.Address = remote.Address
+				member.Certificate = remote.Certificate
+			}
+		}
+
+		err = s.OnNewMemberHook(s.ForRequest(r), state.HookContext{Member: member, Initiator: member.Name})
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to run hook after system %q has joined the cluster: %w", req.Name, err))
 		}
@@ -72,3 +95,14 @@ func hooksPost(s *state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// removeHookContext returns the context for the PreRemove and PostRemove hooks. Members running an older version
+// only send the force flag.
+func removeHookContext(req types.HookRemoveMemberOptions) state.HookContext {
+	hookCtx := state.HookContext{Initiator: req.Initiator, Force: req.Force}
+	if req.Member != nil {
+		hookCtx.Member = *req.Member
+	}
+
+	return hookCtx
+}
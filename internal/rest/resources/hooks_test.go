@@ -1,15 +1,15 @@
 package resources
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
 
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
@@ -17,6 +17,8 @@ import (
 
 	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
 type hooksSuite struct {
@@ -28,41 +30,85 @@ func TestHooksSuite(t *testing.T) {
 }
 
 func (t *hooksSuite) Test_hooks() {
+	certPEM, _, err := shared.GenerateMemCert(false, false)
+	t.NoError(err)
+
+	cert, err := apiTypes.ParseX509Certificate(string(certPEM))
+	t.NoError(err)
+
+	address, err := apiTypes.ParseHostPort("10.0.0.2:8443")
+	t.NoError(err)
+
+	member := &types.ClusterMemberLocal{Name: "n2", Address: address, Certificate: *cert}
+
 	s := &state.State{
 		Context: context.TODO(),
 		Name:    func() string { return "n0" },
+		Remotes: func() *trust.Remotes { return &trust.Remotes{} },
 	}
 
 	var ranHook types.HookType
 	var isForce bool
-	state.PostRemoveHook = func(state *state.State, force bool) error {
+	var hookCtx state.HookContext
+	s.PostRemoveHook = func(state *state.State, ctx state.HookContext) error {
 		ranHook = types.PostRemove
-		isForce = force
+		isForce = ctx.Force
+		hookCtx = ctx
 		return nil
 	}
 
-	state.PreRemoveHook = func(state *state.State, force bool) error {
+	s.PreRemoveHook = func(state *state.State, ctx state.HookContext) error {
 		ranHook = types.PreRemove
-		isForce = force
+		isForce = ctx.Force
+		hookCtx = ctx
 		return nil
 	}
 
-	state.OnNewMemberHook = func(state *state.State) error {
+	s.OnNewMemberHook = func(state *state.State, ctx state.HookContext) error {
 		ranHook = types.OnNewMember
+		hookCtx = ctx
 		return nil
 	}
 
 	tests := []struct {
-		name      string
-		req       any
-		hookType  types.HookType
-		expectErr bool
+		name            string
+		req             any
+		hookType        types.HookType
+		expectErr       bool
+		expectMember    string
+		expectInitiator string
 	}{
 		{
-			name:      "Run OnNewMember hook",
-			req:       types.HookNewMemberOptions{Name: "n1"},
-			hookType:  types.OnNewMember,
-			expectErr: false,
+			name:            "Run OnNewMember hook",
+			req:             types.HookNewMemberOptions{Name: "n1"},
+			hookType:        types.OnNewMember,
+			expectErr:       false,
+			expectMember:    "n1",
+			expectInitiator: "n1",
+		},
+		{
+			name:            "Run OnNewMember hook with the new member",
+			req:             types.HookNewMemberOptions{Name: "n2", Member: member},
+			hookType:        types.OnNewMember,
+			expectErr:       false,
+			expectMember:    "n2",
+			expectInitiator: "n2",
+		},
+		{
+			name:            "Run PostRemove hook with the removed member",
+			req:             types.HookRemoveMemberOptions{Member: member, Initiator: "n1"},
+			hookType:        types.PostRemove,
+			expectErr:       false,
+			expectMember:    "n2",
+			expectInitiator: "n1",
+		},
+		{
+			name:            "Run PreRemove hook with the removed member",
+			req:             types.HookRemoveMemberOptions{Force: true, Member: member, Initiator: "n1"},
+			hookType:        types.PreRemove,
+			expectErr:       false,
+			expectMember:    "n2",
+			expectInitiator: "n1",
 		},
 		{
 			name:      "Run PostRemove hook with force",
@@ -113,23 +159,23 @@ func (t *hooksSuite) Test_hooks() {
 
 		ranHook = ""
 		isForce = false
+		hookCtx = state.HookContext{}
 		expectForce := false
-		req := &http.Request{}
 		payload, ok := c.req.(types.HookRemoveMemberOptions)
-		if !ok {
-			payload, ok := c.req.(types.HookNewMemberOptions)
-			t.True(ok)
-			req.Body = io.NopCloser(strings.NewReader(fmt.Sprintf(`{"name": %q}`, payload.Name)))
-		} else {
+		if ok {
 			expectForce = payload.Force
-			req.Body = io.NopCloser(strings.NewReader(fmt.Sprintf(`{"force": %v}`, expectForce)))
 		}
 
+		body, err := json.Marshal(c.req)
+		require.NoError(t.T(), err)
+
+		req := &http.Request{Body: io.NopCloser(bytes.NewReader(body))}
+
 		req = mux.SetURLVars(req, map[string]string{"hookType": string(c.hookType)})
 
 		response := hooksPost(s, req)
 		recorder := httptest.NewRecorder()
-		err := response.Render(recorder)
+		err = response.Render(recorder)
 		require.NoError(t.T(), err)
 
 		var resp api.Response
@@ -142,6 +188,8 @@ func (t *hooksSuite) Test_hooks() {
 			t.Equal(http.StatusOK, resp.StatusCode)
 			t.Equal(c.hookType, ranHook)
 			t.Equal(expectForce, isForce)
+			t.Equal(c.expectMember, hookCtx.Member.Name)
+			t.Equal(c.expectInitiator, hookCtx.Initiator)
 		} else {
 			t.Equal(api.ErrorResponse, resp.Type)
 			t.NotEqual(api.Success.String(), resp.Status)
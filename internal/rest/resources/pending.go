@@ -0,0 +1,151 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+// DefaultPendingMemberTimeout is how long a cluster member may remain pending without a dqlite node before the leader
+// removes it, unless configured otherwise.
+const DefaultPendingMemberTimeout = 10 * time.Minute
+
+var clusterMemberJoinCmd = rest.Endpoint{
+	Path: "cluster/{name}/join",
+
+	Delete: rest.EndpointAction{Handler: clusterMemberJoinDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+// clusterMemberJoinDelete aborts the join of a pending cluster member, removing its database record, any dqlite node,
+// and its truststore entry. The request is forwarded to the leader, which holds the only truststore entry of a pending member.
+func clusterMemberJoinDelete(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	isLeader, err := s.Database.CheckLeader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !isLeader {
+		client, err := s.Leader()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = client.AbortClusterMemberJoin(ctx, name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer leader.Close()
+
+	err = removePendingMember(ctx, s, leader, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = repairTruststore(ctx, s)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to remove truststore entry of %q: %w", name, err))
+	}
+
+	return response.EmptySyncResponse
+}
+
+// removePendingMember removes the database record of the pending cluster member with the given name, and its dqlite
+// node if the join got that far. The truststore is left for the caller to update.
+func removePendingMember(ctx context.Context, s *state.State, leader *dqliteClient.Client, name string) error {
+	var member *cluster.InternalClusterMember
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		member, err = cluster.GetInternalClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		if member.Role != cluster.Pending {
+			return api.StatusErrorf(http.StatusBadRequest, "Cluster member %q is not pending", name)
+		}
+
+		return cluster.DeleteInternalClusterMember(ctx, tx, member.Address)
+	})
+	if err != nil {
+		return err
+	}
+
+	nodes, err := s.Database.Cluster(ctx, leader)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if node.Address != member.Address {
+			continue
+		}
+
+		err = leader.Remove(ctx, node.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to remove dqlite node of pending cluster member %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeStalePendingMembers removes any pending cluster members that have no dqlite node and are older than
+// the configured timeout, and returns the remaining cluster members.
+func removeStalePendingMembers(ctx context.Context, s *state.State, leader *dqliteClient.Client, clusterMembers []internalTypes.ClusterMember, dqliteNodes []dqliteClient.NodeInfo) []internalTypes.ClusterMember {
+	nodeAddresses := make(map[string]bool, len(dqliteNodes))
+	for _, node := range dqliteNodes {
+		nodeAddresses[node.Address] = true
+	}
+
+	log := logging.FromContext(ctx)
+	remaining := make([]internalTypes.ClusterMember, 0, len(clusterMembers))
+	for _, clusterMember := range clusterMembers {
+		stale := clusterMember.Role == string(cluster.Pending) && !nodeAddresses[clusterMember.Address.String()] && s.Clock.Since(clusterMember.CreatedAt) > s.PendingMemberTimeout
+		if stale {
+			log.Warn("Removing stale pending cluster member", logger.Ctx{"name": clusterMember.Name, "address": clusterMember.Address, "created": clusterMember.CreatedAt})
+			err := removePendingMember(ctx, s, leader, clusterMember.Name)
+			if err == nil {
+				continue
+			}
+
+			log.Error("Failed to remove stale pending cluster member", logger.Ctx{"name": clusterMember.Name, "error": err})
+		}
+
+		remaining = append(remaining, clusterMember)
+	}
+
+	return remaining
+}
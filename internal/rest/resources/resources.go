@@ -5,6 +5,9 @@ import (
 	"github.com/canonical/microcluster/rest"
 )
 
+// untrustedRateLimit is the default rate limit of endpoints that accept requests from untrusted sources.
+var untrustedRateLimit = &rest.RateLimit{Requests: 10, Burst: 20, MaxConcurrent: 20}
+
 // UnixEndpoints are the endpoints available over the unix socket.
 var UnixEndpoints = rest.Resources{
 	Path: rest.EndpointType(client.ControlEndpoint),
@@ -21,8 +24,18 @@ var PublicEndpoints = rest.Resources{
 		api10Cmd,
 		clusterCmd,
 		clusterMemberCmd,
+		clusterMemberJoinCmd,
 		tokensCmd,
+		tokenPublicCmd,
+		clientsCmd,
+		clientCmd,
+		shutdownCmd,
 		readyCmd,
+		healthCmd,
+		healthLiveCmd,
+		tasksCmd,
+		taskCmd,
+		hookRunsCmd,
 	},
 }
 
@@ -38,11 +51,14 @@ var InternalEndpoints = rest.Resources{
 		trustCmd,
 		trustEntryCmd,
 		hooksCmd,
+		consistencyCmd,
 	},
 }
 
-// ExtendedEndpoints holds all the endpoints added by external usage of MicroCluster.
-var ExtendedEndpoints = rest.Resources{
-	Path:      rest.EndpointType(client.ExtendedEndpoint),
-	Endpoints: []rest.Endpoint{},
+// ExtendedEndpoints returns the resources for the endpoints added by external usage of MicroCluster.
+func ExtendedEndpoints(endpoints []rest.Endpoint) rest.Resources {
+	return rest.Resources{
+		Path:      rest.EndpointType(client.ExtendedEndpoint),
+		Endpoints: endpoints,
+	}
 }
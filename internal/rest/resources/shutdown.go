@@ -12,8 +12,9 @@ import (
 )
 
 var shutdownCmd = rest.Endpoint{
-	AllowedBeforeInit: true,
-	Path:              "shutdown",
+	AllowedBeforeInit:     true,
+	AllowedDuringShutdown: true,
+	Path:                  "shutdown",
 
 	Post: rest.EndpointAction{Handler: shutdownPost, AccessHandler: access.AllowAuthenticated},
 }
@@ -35,7 +36,7 @@ func shutdownPost(state *state.State, r *http.Request) response.Response {
 
 		// Send the response before the daemon process ends.
 		f, ok := w.(http.Flusher)
-		if ok {
+		if !ok {
 			return fmt.Errorf("ResponseWriter is not type http.Flusher")
 		}
 
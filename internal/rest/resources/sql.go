@@ -9,11 +9,16 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
+	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
@@ -23,13 +28,34 @@ import (
 var sqlCmd = rest.Endpoint{
 	Path: "sql",
 
-	Get:  rest.EndpointAction{Handler: sqlGet, AccessHandler: access.AllowAuthenticated},
-	Post: rest.EndpointAction{Handler: sqlPost, AccessHandler: access.AllowAuthenticated},
+	Get: rest.EndpointAction{Handler: sqlGet, AccessHandler: access.AllowMember, ProxyTarget: true},
+
+	// Unprivileged users of the unix socket and trusted clients may also run queries, but these are always read-only.
+	// Queries are forwarded to the target member by sqlPost itself, so that read-only mode is kept.
+	Post: rest.EndpointAction{Handler: sqlPost, AllowUntrusted: true, AccessHandler: allowSQLQuery},
+}
+
+// sqlDefaultTimeout is how long a query may run for if the client does not specify a timeout.
+const sqlDefaultTimeout = 30 * time.Second
+
+// allowSQLQuery allows cluster members, any user of the unix socket, and trusted clients to run queries, leaving
+// sqlPost to enforce read-only mode where needed.
+func allowSQLQuery(state *state.State, r *http.Request) response.Response {
+	identity := access.RequestIdentity(r)
+	if identity == nil {
+		return response.Forbidden(nil)
+	}
+
+	if !identity.Member() && identity.Method != access.MethodUnix && identity.Method != access.MethodClient {
+		return response.Forbidden(fmt.Errorf("User %q is not a cluster member", identity.Username))
+	}
+
+	return response.EmptySyncResponse
 }
 
 // Perform a database dump.
 func sqlGet(state *state.State, r *http.Request) response.Response {
-	parentCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	parentCtx, cancel := context.WithTimeout(r.Context(), sqlDefaultTimeout)
 	defer cancel()
 
 	schemaOnly, err := strconv.Atoi(r.FormValue("schema"))
@@ -55,8 +81,6 @@ func sqlGet(state *state.State, r *http.Request) response.Response {
 
 // Execute queries.
 func sqlPost(state *state.State, r *http.Request) response.Response {
-	parentCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
 	req := &types.SQLQuery{}
 	// Parse the request.
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -68,55 +92,282 @@ func sqlPost(state *state.State, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("No query provided"))
 	}
 
+	if req.MaxRows < 0 || req.Timeout < 0 {
+		return response.BadRequest(fmt.Errorf("Row limit and timeout must not be negative"))
+	}
+
+	// Only cluster members and privileged users of the unix socket may write to the database.
+	if access.AllowMember(state, r) != response.EmptySyncResponse {
+		req.ReadOnly = true
+	}
+
+	target := r.FormValue("target")
+	if target != "" && target != state.Name() {
+		return sqlForward(state, r, target, *req)
+	}
+
+	timeout := sqlDefaultTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+
+	parentCtx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
 	// TODO: Handle .sync query.
 
-	batch := types.SQLBatch{}
-	for _, query := range strings.Split(req.Query, ";") {
-		query = strings.TrimLeft(query, " ")
-		if query == "" {
-			continue
+	statements, remainder := cluster.SplitSQL(req.Query)
+	if remainder != "" {
+		statements = append(statements, remainder)
+	}
+
+	if len(statements) == 0 {
+		return response.BadRequest(fmt.Errorf("No query provided"))
+	}
+
+	for _, statement := range statements {
+		err := checkSQLStatement(statement)
+		if err != nil {
+			return response.BadRequest(err)
 		}
+	}
 
-		result := types.SQLResult{}
-		err = state.Database.Transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
-			if strings.HasPrefix(strings.ToUpper(query), "SELECT") {
-				err = sqlSelect(ctx, tx, query, &result)
-			} else {
-				err = sqlExec(ctx, tx, query, &result)
+	// Read-only queries run in a single transaction, on a connection that refuses to write to the database.
+	if req.ReadOnly || req.Transaction {
+		transaction := state.Database.Transaction
+		if req.ReadOnly {
+			transaction = state.Database.ReadOnlyTransaction
+		}
+
+		var batch types.SQLBatch
+		err = transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
+			batch = types.SQLBatch{}
+			for _, statement := range statements {
+				result, err := sqlStatement(ctx, tx, statement, req.MaxRows)
+				if err != nil {
+					return err
+				}
+
+				batch.Results = append(batch.Results, *result)
 			}
 
+			return nil
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, batch)
+	}
+
+	batch := types.SQLBatch{}
+	for _, statement := range statements {
+		var result *types.SQLResult
+		err = state.Database.Transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
+			result, err = sqlStatement(ctx, tx, statement, req.MaxRows)
+
 			return err
 		})
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		batch.Results = append(batch.Results, result)
+		batch.Results = append(batch.Results, *result)
 	}
 
 	return response.SyncResponse(true, batch)
 }
 
-func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
-	result.Type = "select"
-	rows, err := tx.QueryContext(ctx, query)
+// sqlForward runs the query on the target cluster member instead.
+func sqlForward(s *state.State, r *http.Request, target string, req types.SQLQuery) response.Response {
+	remote, ok := s.Remotes().RemotesByName()[target]
+	if !ok {
+		return response.NotFound(fmt.Errorf("No cluster member found with name %q", target))
+	}
+
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
 	if err != nil {
-		return fmt.Errorf("Failed to execute query: %w", err)
+		return response.InternalError(fmt.Errorf("Failed to parse cluster certificate for request: %w", err))
+	}
+
+	url := api.NewURL().Scheme("https").Host(remote.Address.String())
+	c, err := internalClient.NewWithDialer(*url, s.ServerCert(), clusterCert, false, s.Dial)
+	if err != nil {
+		return response.InternalError(fmt.Errorf("Failed to get a client for the target %q: %w", target, err))
+	}
+
+	logging.FromContext(r.Context()).Info("Forwarding query to specified target", logger.Ctx{"source": s.Name(), "target": target})
+	batch, err := c.PostSQL(r.Context(), req)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to run query on target %q: %w", target, err))
+	}
+
+	return response.SyncResponse(true, batch)
+}
+
+// sqlReadPragmas are the pragmas that take an argument in parentheses only to choose what to read.
+var sqlReadPragmas = map[string]bool{
+	"FOREIGN_KEY_CHECK": true,
+	"FOREIGN_KEY_LIST":  true,
+	"INDEX_INFO":        true,
+	"INDEX_LIST":        true,
+	"INDEX_XINFO":       true,
+	"INTEGRITY_CHECK":   true,
+	"QUICK_CHECK":       true,
+	"TABLE_INFO":        true,
+	"TABLE_LIST":        true,
+	"TABLE_XINFO":       true,
+}
+
+// checkSQLStatement refuses statements that would control the transaction the query runs in, attach other database
+// files, or change the settings of the database connection with a pragma. These are refused whether or not the query
+// is read-only, as they could otherwise be used to escape its transaction or connection.
+func checkSQLStatement(statement string) error {
+	tokens := sqlTokens(statement, 5)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	switch tokens[0] {
+	case "BEGIN", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE":
+		return fmt.Errorf("Transaction control statements are not allowed: %q", statement)
+	case "ATTACH", "DETACH":
+		return fmt.Errorf("Attaching and detaching databases is not allowed: %q", statement)
+	case "PRAGMA":
+		// The pragma name may be qualified by a schema name, e.g. "PRAGMA main.table_info(x)".
+		pragma := tokens[1:]
+		if len(pragma) > 2 && pragma[1] == "." {
+			pragma = pragma[2:]
+		}
+
+		if len(pragma) > 1 && (pragma[1] == "=" || (pragma[1] == "(" && !sqlReadPragmas[pragma[0]])) {
+			return fmt.Errorf("Pragmas that change settings are not allowed: %q", statement)
+		}
+	}
+
+	return nil
+}
+
+// sqlTokens returns up to the first n tokens of the statement, skipping comments. Keywords and identifiers are
+// returned in upper case, and any other character is returned as a token of its own.
+func sqlTokens(statement string, n int) []string {
+	tokens := []string{}
+	for i := 0; i < len(statement) && len(tokens) < n; i++ {
+		c := statement[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+		case strings.HasPrefix(statement[i:], "--"):
+			end := strings.Index(statement[i:], "\n")
+			if end < 0 {
+				return tokens
+			}
+
+			i += end
+		case strings.HasPrefix(statement[i:], "/*"):
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+
+			i += end + 3
+		case c == '"' || c == '`' || c == '[':
+			terminator := string(c)
+			if c == '[' {
+				terminator = "]"
+			}
+
+			end := strings.Index(statement[i+1:], terminator)
+			if end < 0 {
+				return append(tokens, strings.ToUpper(statement[i+1:]))
+			}
+
+			tokens = append(tokens, strings.ToUpper(statement[i+1:i+1+end]))
+			i += end + 1
+		case c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			end := i
+			for end < len(statement) && (statement[end] == '_' || unicode.IsLetter(rune(statement[end])) || unicode.IsDigit(rune(statement[end]))) {
+				end++
+			}
+
+			tokens = append(tokens, strings.ToUpper(statement[i:end]))
+			i = end - 1
+		default:
+			tokens = append(tokens, string(c))
+		}
+	}
+
+	return tokens
+}
+
+// sqlStatement runs a single statement. Whether the statement returns rows is determined by the columns of its
+// result, rather than by its text, so that statements such as "WITH ... SELECT" and "PRAGMA" return their rows.
+func sqlStatement(ctx context.Context, tx *sql.Tx, statement string, maxRows int) (*types.SQLResult, error) {
+	var before int64
+	err := tx.QueryRowContext(ctx, "SELECT total_changes()").Scan(&before)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to count changes: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute query: %w", err)
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logger.Error("Failed to close rows after SQL POST request", logger.Ctx{"error": err})
+			logging.FromContext(ctx).Error("Failed to close rows after SQL POST request", logger.Ctx{"error": err})
 		}
 	}()
 
-	result.Columns, err = rows.Columns()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch colume names: %w", err)
+	}
+
+	result := &types.SQLResult{}
+	if len(columns) > 0 {
+		result.Type = "select"
+		result.Columns = columns
+		err = sqlRows(rows, result, maxRows)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	// Some drivers only run the statement once its first row is requested.
+	result.Type = "exec"
+	rows.Next()
+	err = rows.Err()
+	if err == nil {
+		err = rows.Close()
+	}
+
 	if err != nil {
-		return fmt.Errorf("Failed to fetch colume names: %w", err)
+		return nil, fmt.Errorf("Failed to exec query: %w", err)
 	}
 
+	var after int64
+	err = tx.QueryRowContext(ctx, "SELECT total_changes()").Scan(&after)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	result.RowsAffected = after - before
+
+	return result, nil
+}
+
+// sqlRows reads up to maxRows rows into the result, or all of them if maxRows is zero.
+func sqlRows(rows *sql.Rows, result *types.SQLResult, maxRows int) error {
 	for rows.Next() {
+		if maxRows > 0 && len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+
 		row := make([]any, len(result.Columns))
 		rowPointers := make([]any, len(result.Columns))
 		for i := range row {
@@ -140,25 +391,10 @@ func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLR
 		result.Rows = append(result.Rows, row)
 	}
 
-	err = rows.Err()
+	err := rows.Err()
 	if err != nil {
 		return fmt.Errorf("Got a row error: %w", err)
 	}
 
 	return nil
 }
-
-func sqlExec(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
-	result.Type = "exec"
-	r, err := tx.ExecContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("Failed to exec query: %w", err)
-	}
-
-	result.RowsAffected, err = r.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("Failed to fetch affected rows: %w", err)
-	}
-
-	return nil
-}
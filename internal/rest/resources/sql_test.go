@@ -0,0 +1,160 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/db"
+	"github.com/canonical/microcluster/internal/endpoints"
+	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/rest/access"
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+func TestSQLStatement(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := sqlStatement(ctx, tx, "CREATE TABLE test (id INTEGER)", 0)
+	require.NoError(t, err)
+	require.Equal(t, "exec", result.Type)
+
+	result, err = sqlStatement(ctx, tx, "INSERT INTO test VALUES (1), (2), (3)", 0)
+	require.NoError(t, err)
+	require.Equal(t, "exec", result.Type)
+	require.Equal(t, int64(3), result.RowsAffected)
+
+	// Rows are returned for statements that don't start with SELECT.
+	result, err = sqlStatement(ctx, tx, "WITH ids AS (SELECT id FROM test) SELECT id FROM ids ORDER BY id", 2)
+	require.NoError(t, err)
+	require.Equal(t, "select", result.Type)
+	require.Equal(t, []string{"id"}, result.Columns)
+	require.Equal(t, [][]any{{int64(1)}, {int64(2)}}, result.Rows)
+	require.True(t, result.Truncated)
+
+	result, err = sqlStatement(ctx, tx, "PRAGMA table_info(test)", 0)
+	require.NoError(t, err)
+	require.Equal(t, "select", result.Type)
+	require.Len(t, result.Rows, 1)
+	require.False(t, result.Truncated)
+}
+
+func TestCheckSQLStatement(t *testing.T) {
+	allowed := []string{
+		"SELECT 1",
+		"INSERT INTO test VALUES (1)",
+		"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET x=1; END",
+		"PRAGMA table_info(test)",
+		"PRAGMA main.table_info(test)",
+		"PRAGMA foreign_keys",
+	}
+
+	for _, statement := range allowed {
+		require.NoError(t, checkSQLStatement(statement), statement)
+	}
+
+	refused := []string{
+		"COMMIT",
+		"/* comment */ end transaction",
+		"-- comment\nBEGIN IMMEDIATE",
+		"ROLLBACK TO sp",
+		"SAVEPOINT sp",
+		"RELEASE sp",
+		"ATTACH DATABASE '/tmp/other.db' AS other",
+		"DETACH other",
+		"PRAGMA query_only = 0",
+		"PRAGMA query_only(0)",
+		"pragma main.foreign_keys=OFF",
+		"PRAGMA \"writable_schema\" = 1",
+	}
+
+	for _, statement := range refused {
+		require.Error(t, checkSQLStatement(statement), statement)
+	}
+}
+
+// newTestDatabase returns an embedded database with the core schema, which is stopped at the end of the test.
+func newTestDatabase(t *testing.T) *db.DB {
+	sysOS, err := sys.DefaultOS(t.TempDir(), "", true)
+	require.NoError(t, err)
+
+	ext, err := extensions.NewExtensionRegistry(true)
+	require.NoError(t, err)
+
+	addr := *api.NewURL().Host("10.0.0.0:8443")
+	database := db.NewDB(context.Background(), nil, nil, sysOS, nil, clock.New())
+	database.SetSchema(nil, ext)
+	err = database.BootstrapEmbedded(ext, cluster.GetCallerProject(), addr, cluster.InternalClusterMember{Name: "member0", Address: addr.URL.Host, Certificate: "test-cert", Role: cluster.Pending})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, database.Stop()) })
+
+	return database
+}
+
+// Ensures read-only queries can't write to the database, even by ending their transaction.
+func TestSQLPostReadOnly(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDatabase(t)
+	s := &state.State{Database: database}
+	user := &access.Identity{Method: access.MethodUnix, Credentials: &endpoints.PeerCredentials{UID: uint32(os.Getuid()) + 1}}
+	client := &access.Identity{Method: access.MethodClient, Username: "client"}
+
+	post := func(query types.SQLQuery) *httptest.ResponseRecorder {
+		body, err := json.Marshal(query)
+		require.NoError(t, err)
+
+		r := access.SetRequestIdentity(httptest.NewRequest("POST", "/cluster/internal/sql", bytes.NewReader(body)), user)
+		w := httptest.NewRecorder()
+		require.NoError(t, sqlPost(s, r).Render(w))
+
+		return w
+	}
+
+	insert := "INSERT INTO internal_token_records (name, secret) VALUES ('member1', 'secret')"
+	require.Equal(t, http.StatusBadRequest, post(types.SQLQuery{Query: "COMMIT; " + insert}).Code)
+	require.Equal(t, http.StatusBadRequest, post(types.SQLQuery{Query: "PRAGMA query_only = 0; " + insert}).Code)
+
+	// Unprivileged users can't turn off read-only mode, which is enforced by the database.
+	require.Contains(t, post(types.SQLQuery{Query: insert}).Body.String(), "readonly database")
+	require.Contains(t, post(types.SQLQuery{Query: insert, Transaction: true}).Body.String(), "readonly database")
+	require.Equal(t, http.StatusOK, post(types.SQLQuery{Query: "SELECT count(*) FROM internal_token_records"}).Code)
+
+	// Trusted clients are privileged, but aren't cluster members, so their queries are read-only too.
+	user = client
+	require.Contains(t, post(types.SQLQuery{Query: insert, Transaction: true}).Body.String(), "readonly database")
+	require.Equal(t, http.StatusOK, post(types.SQLQuery{Query: "SELECT count(*) FROM internal_token_records"}).Code)
+
+	var count int
+	err := database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT count(*) FROM internal_token_records").Scan(&count)
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// The connection is writable again for other transactions.
+	err = database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, insert)
+		return err
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,64 @@
+package resources
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/internal/logging"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/tasks"
+)
+
+var tasksCmd = rest.Endpoint{
+	Path: "tasks",
+
+	Get: rest.EndpointAction{Handler: tasksGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var taskCmd = rest.Endpoint{
+	Path: "tasks/{name}",
+
+	Post: rest.EndpointAction{Handler: taskPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// tasksGet returns the status of the background tasks registered on this cluster member.
+func tasksGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.Tasks().Status())
+}
+
+// taskPost runs the named task as soon as possible. Requests for leader-only tasks are forwarded to the leader.
+func taskPost(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.Tasks().Trigger(r.Context(), name)
+	if errors.Is(err, tasks.ErrNotLeader) && !client.IsNotification(r) {
+		leader, err := s.Leader()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		leader.SetClusterNotification()
+		err = leader.TriggerTask(logging.Inherit(s.Context, r.Context()), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
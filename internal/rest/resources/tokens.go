@@ -4,15 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
@@ -30,6 +37,13 @@ var tokensCmd = rest.Endpoint{
 var tokenCmd = rest.Endpoint{
 	Path: "tokens/{name}",
 
+	Delete: rest.EndpointAction{Handler: tokenDelete, AccessHandler: access.AllowMember},
+}
+
+// tokenPublicCmd lets remote clients revoke tokens, which they can't do through the member-only internal endpoint.
+var tokenPublicCmd = rest.Endpoint{
+	Path: "tokens/{name}",
+
 	Delete: rest.EndpointAction{Handler: tokenDelete, AccessHandler: access.AllowAuthenticated},
 }
 
@@ -42,6 +56,14 @@ func tokensPost(state *state.State, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Other members can only join once an embedded database has been converted to dqlite.
+	if state.Database.IsEmbedded() {
+		err = state.ConvertEmbedded()
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	// Generate join token for new member. This will be stored alongside the join
 	// address and cluster certificate to simplify setup.
 	tokenKey, err := shared.RandomCryptoString()
@@ -54,14 +76,14 @@ func tokensPost(state *state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	joinAddresses := []types.AddrPort{}
+	joinAddresses := []types.HostPort{}
 	for _, addr := range state.Remotes().Addresses() {
 		joinAddresses = append(joinAddresses, addr)
 	}
 
 	if len(joinAddresses) == 0 {
-		logger.Warnf("Failed to check trust store for eligible join addresses. Issuing token with join address %q", state.Address().URL.Host)
-		joinAddresses, err = types.ParseAddrPorts([]string{state.Address().URL.Host})
+		logging.FromContext(r.Context()).Warn("Failed to check trust store for eligible join addresses, issuing token with local address", logger.Ctx{"address": state.Address().URL.Host})
+		joinAddresses, err = types.ParseHostPorts([]string{state.Address().URL.Host})
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -95,7 +117,7 @@ func tokensGet(state *state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	joinAddresses := []types.AddrPort{}
+	joinAddresses := []types.HostPort{}
 	for _, addr := range state.Remotes().Addresses() {
 		joinAddresses = append(joinAddresses, addr)
 	}
@@ -142,3 +164,90 @@ func tokenDelete(state *state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// joinSource returns the address of the request's sender, without its port.
+func joinSource(r *http.Request) string {
+	source, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return source
+}
+
+// errJoinRejected is returned to the sender of a join request with an invalid token.
+// It is deliberately vague about whether a token by the requested name exists.
+var errJoinRejected = api.StatusErrorf(http.StatusForbidden, "Invalid join token")
+
+// checkJoinToken verifies the join token presented by an untrusted join request, before it is forwarded to the leader.
+// Failed attempts are counted in memory by this cluster member against the source address, and against the token
+// issued under the requested name if there is one. Once either has failed too many times, further attempts with an invalid secret are locked out
+// for exponentially longer. A valid secret is always accepted from a source that is not locked out, so that guesses
+// under a token's name can't lock out the cluster member it was issued for.
+func checkJoinToken(s *state.State, r *http.Request, name string, secret string) response.Response {
+	log := logging.FromContext(r.Context())
+	source := joinSource(r)
+	now := s.Clock.Now()
+
+	wait := s.JoinGuard.LockedOut(source, now)
+	if wait > 0 {
+		log.Warn("Rejected join attempt from locked out address", logger.Ctx{"name": name, "source": source})
+		return tooManyJoinAttempts(wait)
+	}
+
+	var record *cluster.InternalTokenRecord
+	var nameExists bool
+	err := s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := cluster.GetInternalTokenRecords(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		record = cluster.MatchInternalTokenRecord(records, secret)
+		for _, nameRecord := range records {
+			if nameRecord.Name == name {
+				nameExists = true
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to check join token: %w", err))
+	}
+
+	if record != nil {
+		s.JoinGuard.Accept(source, record.Name)
+
+		return response.EmptySyncResponse
+	}
+
+	failures, wait := s.JoinGuard.Reject(source, name, nameExists, now)
+	log.Warn("Rejected join attempt with invalid token", logger.Ctx{"name": name, "source": source, "failures": failures})
+
+	err = s.OnJoinRejectedHook(s.ForRequest(r), name, source, failures)
+	if err != nil {
+		log.Error("Failed to run OnJoinRejected hook", logger.Ctx{"name": name, "source": source, "error": err})
+	}
+
+	if wait > 0 {
+		return tooManyJoinAttempts(wait)
+	}
+
+	return response.SmartError(errJoinRejected)
+}
+
+// tooManyJoinAttempts rejects a join attempt that was locked out, telling the sender how long to wait.
+func tooManyJoinAttempts(wait time.Duration) response.Response {
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		seconds := int(math.Ceil(wait.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+		return response.ErrorResponse(http.StatusTooManyRequests, "Too many failed join attempts").Render(w)
+	})
+}
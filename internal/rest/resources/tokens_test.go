@@ -0,0 +1,57 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// Ensures guessing under a token's name doesn't lock out the cluster member with the valid secret.
+func TestCheckJoinToken(t *testing.T) {
+	database := newTestDatabase(t)
+	err := database.Transaction(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateInternalTokenRecord(ctx, tx, cluster.InternalTokenRecord{Name: "member1", Secret: "secret"})
+		return err
+	})
+	require.NoError(t, err)
+
+	fakeClock := clock.NewFake(time.Now())
+	s := &state.State{
+		Context:            context.Background(),
+		Database:           database,
+		Clock:              fakeClock,
+		JoinGuard:          trust.NewJoinGuard(),
+		OnJoinRejectedHook: func(s *state.State, name string, source string, failures int) error { return nil },
+	}
+
+	check := func(source string, secret string) int {
+		r := httptest.NewRequest("POST", "/cluster/1.0/cluster", nil)
+		r.RemoteAddr = source + ":8443"
+		w := httptest.NewRecorder()
+		require.NoError(t, checkJoinToken(s, r, "member1", secret).Render(w))
+
+		return w.Code
+	}
+
+	// Guesses from many sources lock out further guesses under the name.
+	for i := 0; i < 10; i++ {
+		check(fmt.Sprintf("10.0.0.%d", i+1), "guess")
+	}
+
+	require.Equal(t, http.StatusTooManyRequests, check("10.0.1.1", "guess"))
+
+	// The valid secret is still accepted, after which guesses under the name are no longer locked out.
+	require.Equal(t, http.StatusOK, check("10.0.2.1", "secret"))
+	require.Equal(t, http.StatusForbidden, check("10.0.3.1", "guess"))
+}
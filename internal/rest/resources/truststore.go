@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/internal/logging"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
@@ -24,14 +25,14 @@ var trustCmd = rest.Endpoint{
 	Path:              "truststore",
 	AllowedBeforeInit: true,
 
-	Post: rest.EndpointAction{Handler: trustPost, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: trustPost, AccessHandler: access.AllowMember},
 }
 
 var trustEntryCmd = rest.Endpoint{
 	Path:              "truststore/{name}",
 	AllowedBeforeInit: true,
 
-	Delete: rest.EndpointAction{Handler: trustDelete, AccessHandler: access.AllowAuthenticated},
+	Delete: rest.EndpointAction{Handler: trustDelete, AccessHandler: access.AllowMember},
 }
 
 func trustPost(s *state.State, r *http.Request) response.Response {
@@ -48,7 +49,7 @@ func trustPost(s *state.State, r *http.Request) response.Response {
 		Certificate: req.Certificate,
 	}
 
-	ctx, cancel := context.WithTimeout(s.Context, 30*time.Second)
+	ctx, cancel := context.WithTimeout(logging.Inherit(s.Context, r.Context()), 30*time.Second)
 	defer cancel()
 
 	if !client.IsNotification(r) {
@@ -89,7 +90,7 @@ func trustDelete(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(s.Context, 30*time.Second)
+	ctx, cancel := context.WithTimeout(logging.Inherit(s.Context, r.Context()), 30*time.Second)
 	defer cancel()
 
 	remotesMap := s.Remotes().RemotesByName()
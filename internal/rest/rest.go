@@ -8,21 +8,32 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
+	"time"
 
-	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/logging"
 	internalAccess "github.com/canonical/microcluster/internal/rest/access"
 	"github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/tracing"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
 )
 
+// shutdownRetryAfter is the number of seconds a client is asked to wait before retrying a request rejected during shutdown.
+const shutdownRetryAfter = 10
+
 func handleAPIRequest(action rest.EndpointAction, state *state.State, w http.ResponseWriter, r *http.Request) response.Response {
 	if action.Handler == nil {
 		return response.NotImplemented(nil)
@@ -56,9 +67,10 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 		return action.Handler(s, r)
 	}
 
+	log := logging.FromContext(r.Context())
 	values, err := url.ParseQuery(r.URL.RawQuery)
 	if err != nil {
-		logger.Warnf("Failed to parse query string %q: %v", r.URL.RawQuery, err)
+		log.Warn("Failed to parse query string", logger.Ctx{"query": r.URL.RawQuery, "error": err})
 	}
 
 	var target string
@@ -90,7 +102,7 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 		return response.InternalError(fmt.Errorf("Failed to parse cluster certificate for request: %w", err))
 	}
 
-	client, err := client.New(*targetURL, s.ServerCert(), clusterCert, false)
+	client, err := client.NewWithDialer(*targetURL, s.ServerCert(), clusterCert, false, s.Dial)
 	if err != nil {
 		return response.InternalError(fmt.Errorf("Failed to get a client for the target %q at address %q: %w", target, targetURL.String(), err))
 	}
@@ -101,7 +113,8 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 	r.URL.Host = targetURL.URL.Host
 	r.Host = targetURL.URL.Host
 
-	logger.Info("Forwarding request to specified target", logger.Ctx{"source": s.Name(), "target": target})
+	// The request ID header is forwarded along with the request, so the target logs under the same ID.
+	log.Info("Forwarding request to specified target", logger.Ctx{"source": s.Name(), "target": target})
 	resp, err := client.MakeRequest(r)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Failed to send request to target %q: %w", target, err))
@@ -111,17 +124,9 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 }
 
 func handleDatabaseRequest(action rest.EndpointAction, state *state.State, w http.ResponseWriter, r *http.Request) response.Response {
-	trusted := r.Context().Value(request.CtxAccess)
-	if trusted == nil {
-		return response.Forbidden(nil)
-	}
-
-	trustedReq, ok := trusted.(internalAccess.TrustedRequest)
-	if !ok {
-		return response.Forbidden(nil)
-	}
-
-	if !trustedReq.Trusted {
+	// Only cluster members may connect to the database.
+	identity := internalAccess.GetRequestIdentity(r)
+	if identity == nil || identity.Method != internalAccess.MethodTLS {
 		return response.Forbidden(nil)
 	}
 
@@ -147,35 +152,183 @@ func handleDatabaseRequest(action rest.EndpointAction, state *state.State, w htt
 	return action.Handler(state, r)
 }
 
+// maxRequestIDLength is the maximum length of a request ID received from a client before it is replaced.
+const maxRequestIDLength = 128
+
+// withRequestLogger returns a copy of the request whose context carries a request ID, and a logger that includes the
+// request ID, the caller, and the name and dqlite role of this cluster member with every message.
+// Requests forwarded from another cluster member keep the request ID they were sent with.
+func withRequestLogger(s *state.State, r *http.Request) *http.Request {
+	requestID := r.Header.Get(logging.HeaderRequestID)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		requestID = uuid.New().String()
+		r.Header.Set(logging.HeaderRequestID, requestID)
+	}
+
+	caller := r.RemoteAddr
+	if caller == "@" {
+		caller = "local"
+	} else if r.TLS != nil && s.Remotes() != nil {
+		for _, cert := range r.TLS.PeerCertificates {
+			remote := s.Remotes().RemoteByCertificateFingerprint(shared.CertFingerprint(cert))
+			if remote != nil {
+				caller = remote.Name
+				break
+			}
+		}
+	}
+
+	log := logger.AddContext(logger.Ctx{"request_id": requestID, "caller": caller, "member": s.Name(), "role": s.Database.Role()})
+	ctx := logging.WithLogger(logging.WithRequestID(r.Context(), requestID), log)
+
+	return r.WithContext(ctx)
+}
+
+// statusRecorder records the status code of a response so that it can be added to the request span.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher, as some handlers flush their response before the daemon restarts.
+func (w *statusRecorder) Flush() {
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// rejectTooManyRequests responds with Too Many Requests (429), asking the client to retry after the given wait.
+func rejectTooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration, reason error) {
+	log := logging.FromContext(r.Context())
+	log.Debug("Rejecting request", logger.Ctx{"url": r.URL.String(), "remote": r.RemoteAddr, "reason": reason})
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+	err := response.ErrorResponse(http.StatusTooManyRequests, reason.Error()).Render(w)
+	if err != nil {
+		log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+	}
+}
+
+// authenticate returns the identity established by the first authenticator to recognize the request's credentials,
+// or nil if none do. The given authenticators are consulted in order after the built-in TLS authentication.
+func authenticate(s *state.State, r *http.Request, authenticators []access.Authenticator) (*internalAccess.Identity, error) {
+	authenticators = append([]access.Authenticator{access.TLSAuthenticator{}, access.ClientCertificateAuthenticator{}}, authenticators...)
+	for _, authenticator := range authenticators {
+		identity, err := authenticator.Authenticate(s, r)
+		if err != nil {
+			// A request addressed to an unexpected host is not trusted through TLS, but may still be through other means.
+			if errors.As(err, &access.ErrInvalidHost{}) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if identity != nil {
+			return identity, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // HandleEndpoint adds the endpoint to the mux router. A function variable is used to implement common logic
 // before calling the endpoint action handler associated with the request method, if it exists.
-func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.Endpoint) {
+// The authenticators identify the sender of requests that are not from a cluster member or over the unix socket.
+func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.Endpoint, authenticators []access.Authenticator) {
 	url := "/" + version
 	if e.Path != "" {
 		url = filepath.Join(url, e.Path)
 	}
 
+	limiter := newRateLimiter(e.RateLimit)
+
 	route := mux.HandleFunc(url, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
+		r = withRequestLogger(state, r)
+		w.Header().Set(logging.HeaderRequestID, logging.RequestID(r.Context()))
+		log := logging.FromContext(r.Context())
+		log.Debug("Handling API request", logger.Ctx{"method": r.Method, "url": r.URL.String()})
+
+		// Trace the request, continuing any trace sent by the caller. Database connections are long-lived, so are not traced.
+		if e.Path != "database" {
+			ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), r.Method+" "+url, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", url),
+				attribute.String("microcluster.request_id", logging.RequestID(r.Context())),
+				attribute.String("microcluster.member", state.Name()),
+			))
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				span.SetAttributes(attribute.Int("http.status_code", recorder.status))
+				if recorder.status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(recorder.status))
+				}
+
+				span.End()
+			}()
+
+			r = r.WithContext(ctx)
+			w = recorder
+		}
+
+		// Limit the rate and concurrency of network requests, if configured for the endpoint.
+		if limiter != nil && r.RemoteAddr != "@" {
+			ok, wait := limiter.allow(r.RemoteAddr, state.Clock.Now())
+			if !ok {
+				rejectTooManyRequests(w, r, wait, fmt.Errorf("Rate limit exceeded"))
+				return
+			}
+
+			release, ok := limiter.acquire()
+			if !ok {
+				rejectTooManyRequests(w, r, time.Second, fmt.Errorf("Too many concurrent requests"))
+				return
+			}
+
+			defer release()
+		}
+
 		// Actually process the request.
 		var resp response.Response
 
 		// Return Unavailable Error (503) if daemon is shutting down, except for endpoints with AllowedDuringShutdown.
-		if state.Context.Err() == context.Canceled && !e.AllowedDuringShutdown {
-			err := response.Unavailable(fmt.Errorf("Daemon is shutting down")).Render(w)
-			if err != nil {
-				logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+		// Other requests are tracked so that the daemon can wait for them to complete before shutting down. New
+		// requests are refused as soon as draining starts, which is before the shutdown context is cancelled.
+		if !e.AllowedDuringShutdown {
+			var requestDone func()
+			ok := state.Context.Err() != context.Canceled
+			if ok {
+				requestDone, ok = state.Requests.Begin()
+			}
+
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(shutdownRetryAfter))
+				err := response.Unavailable(fmt.Errorf("Daemon is shutting down")).Render(w)
+				if err != nil {
+					log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+				}
+
+				return
 			}
 
-			return
+			defer requestDone()
 		}
 
 		if !e.AllowedBeforeInit {
 			if !state.Database.IsOpen() {
 				err := response.Unavailable(fmt.Errorf("Daemon not yet initialized")).Render(w)
 				if err != nil {
-					logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+					log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 				}
 
 				return
@@ -188,25 +341,36 @@ func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.
 			handleRequest = handleDatabaseRequest
 		}
 
-		trusted, err := access.Authenticate(state, r, state.Address().URL.Host, state.Remotes().CertificatesNative())
-		if err != nil && !errors.As(err, &access.ErrInvalidHost{}) {
-			resp = response.Forbidden(fmt.Errorf("Failed to authenticate request: %w", err))
-		} else {
-			r = internalAccess.SetRequestAuthentication(r, trusted)
-
-			switch r.Method {
-			case "GET":
-				resp = handleRequest(e.Get, state, w, r)
-			case "PUT":
-				resp = handleRequest(e.Put, state, w, r)
-			case "POST":
-				resp = handleRequest(e.Post, state, w, r)
-			case "DELETE":
-				resp = handleRequest(e.Delete, state, w, r)
-			case "PATCH":
-				resp = handleRequest(e.Patch, state, w, r)
-			default:
-				resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
+		var action *rest.EndpointAction
+		switch r.Method {
+		case "GET":
+			action = &e.Get
+		case "PUT":
+			action = &e.Put
+		case "POST":
+			action = &e.Post
+		case "DELETE":
+			action = &e.Delete
+		case "PATCH":
+			action = &e.Patch
+		default:
+			resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
+		}
+
+		if action != nil {
+			identity, err := authenticate(state, r, authenticators)
+			if err != nil && action.AllowUntrusted {
+				// Credentials that fail to authenticate, e.g. an expired token, don't stop the request being handled
+				// as untrusted.
+				log.Debug("Handling request as untrusted after failing to authenticate it", logger.Ctx{"url": r.URL.String(), "error": err})
+				identity, err = nil, nil
+			}
+
+			if err != nil {
+				resp = response.Forbidden(fmt.Errorf("Failed to authenticate request: %w", err))
+			} else {
+				r = internalAccess.SetRequestIdentity(r, identity)
+				resp = handleRequest(*action, state, w, r)
 			}
 		}
 
@@ -216,7 +380,7 @@ func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.
 			if err != nil {
 				err := response.InternalError(err).Render(w)
 				if err != nil {
-					logger.Error("Failed writing error for HTTP response", logger.Ctx{"url": url, "error": err})
+					log.Error("Failed writing error for HTTP response", logger.Ctx{"url": url, "error": err})
 				}
 			}
 		}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/internal/rest/access"
+	"github.com/canonical/microcluster/internal/rest/resources"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/rest"
+	restAccess "github.com/canonical/microcluster/rest/access"
+)
+
+// failingAuthenticator rejects the credentials of every request.
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) Authenticate(state *state.State, r *http.Request) (*restAccess.Identity, error) {
+	return nil, fmt.Errorf("Token has expired")
+}
+
+// Ensures credentials that fail to authenticate only stop requests to endpoints that need a trusted sender.
+func TestHandleEndpointFailedAuthentication(t *testing.T) {
+	s := &state.State{
+		Context: context.Background(),
+		Name:    func() string { return "member0" },
+		Address: func() *api.URL { return api.NewURL().Scheme("https").Host("10.0.0.1:8443") },
+		Remotes: func() *trust.Remotes { return &trust.Remotes{} },
+	}
+
+	handler := func(state *state.State, r *http.Request) response.Response { return response.EmptySyncResponse }
+	router := mux.NewRouter()
+	for _, e := range []rest.Endpoint{
+		{Path: "untrusted", Get: rest.EndpointAction{Handler: handler, AllowUntrusted: true}},
+		{Path: "trusted", Get: rest.EndpointAction{Handler: handler}},
+	} {
+		e.AllowedBeforeInit = true
+		e.AllowedDuringShutdown = true
+		HandleEndpoint(s, router, "1.0", e, []restAccess.Authenticator{failingAuthenticator{}})
+	}
+
+	for path, status := range map[string]int{"untrusted": http.StatusOK, "trusted": http.StatusForbidden} {
+		r := httptest.NewRequest("GET", "https://10.0.0.1:8443/1.0/"+path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, status, w.Code, path)
+	}
+}
+
+// Ensures senders that are not cluster members can't use the endpoints that members use to coordinate.
+func TestHandleAPIRequestMemberOnly(t *testing.T) {
+	identities := []*access.Identity{
+		{Method: access.MethodBearer, Username: "bearer"},
+		{Method: access.MethodOIDC, Username: "oidc", Groups: []string{"admins"}},
+		{Method: access.MethodClient, Username: "client"},
+	}
+
+	endpoints := append([]rest.Endpoint{}, resources.InternalEndpoints.Endpoints...)
+	for _, e := range resources.PublicEndpoints.Endpoints {
+		if e.Path == "cluster/{name}" {
+			endpoints = append(endpoints, rest.Endpoint{Path: e.Path, Put: e.Put})
+		}
+	}
+
+	for _, e := range endpoints {
+		actions := map[string]rest.EndpointAction{"GET": e.Get, "PUT": e.Put, "POST": e.Post, "DELETE": e.Delete, "PATCH": e.Patch}
+		for method, action := range actions {
+			if action.Handler == nil {
+				continue
+			}
+
+			for _, identity := range identities {
+				// Trusted clients may run read-only queries, which sqlPost enforces.
+				if e.Path == "sql" && method == "POST" && identity.Method == access.MethodClient {
+					continue
+				}
+
+				r := access.SetRequestIdentity(httptest.NewRequest(method, "/cluster/internal/"+e.Path, nil), identity)
+				w := httptest.NewRecorder()
+
+				// The database endpoint checks the sender in handleDatabaseRequest instead.
+				if e.Path == "database" {
+					require.NoError(t, handleDatabaseRequest(action, nil, w, r).Render(w))
+				} else {
+					require.NoError(t, handleAPIRequest(action, nil, w, r).Render(w))
+				}
+
+				require.Equal(t, http.StatusForbidden, w.Code, "%s %s as %s", method, e.Path, identity.Method)
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"time"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// ClientCertificate represents a client outside of the cluster that is trusted to use its API.
+type ClientCertificate struct {
+	Name        string    `json:"name" yaml:"name"`
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// ClientCertificatePost requests that a client's certificate be trusted, with a join token issued under its name.
+type ClientCertificatePost struct {
+	Name        string                `json:"name" yaml:"name"`
+	Certificate types.X509Certificate `json:"certificate" yaml:"certificate"`
+	Secret      string                `json:"secret" yaml:"secret"`
+}
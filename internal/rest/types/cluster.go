@@ -14,6 +14,7 @@ type ClusterMember struct {
 	SchemaInternalVersion uint64                `json:"schema_internal_version" yaml:"schema_internal_version"`
 	SchemaExternalVersion uint64                `json:"schema_external_version" yaml:"schema_external_version"`
 	LastHeartbeat         time.Time             `json:"last_heartbeat" yaml:"last_heartbeat"`
+	CreatedAt             time.Time             `json:"created_at" yaml:"created_at"`
 	Status                MemberStatus          `json:"status" yaml:"status"`
 	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
 	Secret                string                `json:"secret" yaml:"secret"`
@@ -22,7 +23,7 @@ type ClusterMember struct {
 // ClusterMemberLocal represents local information about a new cluster member.
 type ClusterMemberLocal struct {
 	Name        string                `json:"name" yaml:"name"`
-	Address     types.AddrPort        `json:"address" yaml:"address"`
+	Address     types.HostPort        `json:"address" yaml:"address"`
 	Certificate types.X509Certificate `json:"certificate" yaml:"certificate"`
 }
 
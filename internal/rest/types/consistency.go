@@ -0,0 +1,55 @@
+package types
+
+// ConsistencyIssueType is a kind of disagreement between the truststore, the database, and dqlite.
+type ConsistencyIssueType string
+
+const (
+	// ConsistencyMissingRemote is a database cluster member with no entry in the local truststore.
+	ConsistencyMissingRemote ConsistencyIssueType = "missing_remote"
+
+	// ConsistencyOrphanedRemote is an entry in the local truststore with no database cluster member.
+	ConsistencyOrphanedRemote ConsistencyIssueType = "orphaned_remote"
+
+	// ConsistencyRemoteMismatch is an entry in the local truststore whose address or certificate differs from the
+	// database cluster member with the same name.
+	ConsistencyRemoteMismatch ConsistencyIssueType = "remote_mismatch"
+
+	// ConsistencyPendingMember is a pending database cluster member with no dqlite node.
+	ConsistencyPendingMember ConsistencyIssueType = "pending_member"
+
+	// ConsistencyMissingNode is a non-pending database cluster member with no dqlite node.
+	ConsistencyMissingNode ConsistencyIssueType = "missing_node"
+
+	// ConsistencyOrphanedNode is a dqlite node with no database cluster member.
+	ConsistencyOrphanedNode ConsistencyIssueType = "orphaned_node"
+)
+
+// Local returns whether the issue only concerns the local truststore, rather than cluster-wide state.
+func (c ConsistencyIssueType) Local() bool {
+	switch c {
+	case ConsistencyMissingRemote, ConsistencyOrphanedRemote, ConsistencyRemoteMismatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConsistencyIssue is a single disagreement between the truststore, the database, and dqlite.
+type ConsistencyIssue struct {
+	Type    ConsistencyIssueType `json:"type" yaml:"type"`
+	Name    string               `json:"name" yaml:"name"`
+	Address string               `json:"address" yaml:"address"`
+	Message string               `json:"message" yaml:"message"`
+}
+
+// ConsistencyReport is the result of comparing the truststore, the database, and dqlite on a cluster member.
+type ConsistencyReport struct {
+	Member string             `json:"member" yaml:"member"`
+	Issues []ConsistencyIssue `json:"issues" yaml:"issues"`
+}
+
+// ConsistencyRepair is a request to repair the given issues.
+// Only issues that are still present when the repair is run will be repaired.
+type ConsistencyRepair struct {
+	Issues []ConsistencyIssue `json:"issues" yaml:"issues"`
+}
@@ -9,6 +9,6 @@ type Control struct {
 	Bootstrap  bool              `json:"bootstrap" yaml:"bootstrap"`
 	InitConfig map[string]string `json:"config" yaml:"config"`
 	JoinToken  string            `json:"join_token" yaml:"join_token"`
-	Address    types.AddrPort    `json:"address" yaml:"address"`
+	Address    types.HostPort    `json:"address" yaml:"address"`
 	Name       string            `json:"name" yaml:"name"`
 }
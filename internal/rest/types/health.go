@@ -0,0 +1,50 @@
+package types
+
+// HealthStatus is the health of the daemon or one of its components.
+type HealthStatus string
+
+const (
+	// HealthStatusHealthy means the component is working as expected.
+	HealthStatusHealthy HealthStatus = "healthy"
+
+	// HealthStatusDegraded means the component is working, but needs attention.
+	HealthStatusDegraded HealthStatus = "degraded"
+
+	// HealthStatusUnhealthy means the component is not working.
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// Health represents the health of the daemon, as the worst status of each of its components.
+type Health struct {
+	Status     HealthStatus      `json:"status" yaml:"status"`
+	Components []HealthComponent `json:"components" yaml:"components"`
+}
+
+// HealthComponent represents the health of a single component of the daemon.
+type HealthComponent struct {
+	Name    string            `json:"name" yaml:"name"`
+	Status  HealthStatus      `json:"status" yaml:"status"`
+	Message string            `json:"message" yaml:"message"`
+	Details map[string]string `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// severity orders health statuses from best to worst.
+func (h HealthStatus) severity() int {
+	switch h {
+	case HealthStatusHealthy:
+		return 0
+	case HealthStatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Worse returns whichever of the two statuses is the least healthy.
+func (h HealthStatus) Worse(other HealthStatus) HealthStatus {
+	if other.severity() > h.severity() {
+		return other
+	}
+
+	return h
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"time"
+)
+
 // HookType represents the various types of hooks available to microcluster.
 type HookType string
 
@@ -32,16 +36,62 @@ const (
 
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat HookType = "on-heartbeat"
+
+	// OnJoinRejected is run on the cluster member that rejected a join attempt with an invalid token.
+	OnJoinRejected HookType = "on-join-rejected"
+
+	// OnLeaderElected is run when a cluster member becomes the dqlite leader.
+	OnLeaderElected HookType = "on-leader-elected"
+
+	// OnLeaderLost is run when a cluster member stops being the dqlite leader.
+	OnLeaderLost HookType = "on-leader-lost"
+
+	// PreShutdown is run when the daemon is shutting down, before the database is closed.
+	PreShutdown HookType = "pre-shutdown"
 )
 
+// HookRun records a single run of a hook on a cluster member, including any retries.
+type HookRun struct {
+	Hook       HookType  `json:"hook" yaml:"hook"`
+	Member     string    `json:"member" yaml:"member"`
+	Async      bool      `json:"async" yaml:"async"`
+	StartedAt  time.Time `json:"started_at" yaml:"started_at"`
+	FinishedAt time.Time `json:"finished_at" yaml:"finished_at"`
+	Attempts   int       `json:"attempts" yaml:"attempts"`
+	Error      string    `json:"error" yaml:"error"`
+}
+
 // HookRemoveMemberOptions holds configuration pertaining to the PreRemove and PostRemove hooks.
 type HookRemoveMemberOptions struct {
 	// Force represents whether to run the hook with the `force` option.
 	Force bool `json:"force" yaml:"force"`
+
+	// Member is the cluster member being removed.
+	Member *ClusterMemberLocal `json:"member,omitempty" yaml:"member,omitempty"`
+
+	// Initiator is the name of the cluster member removing the member.
+	Initiator string `json:"initiator" yaml:"initiator"`
 }
 
 // HookNewMemberOptions holds configuration pertaining to the OnNewMember hook.
 type HookNewMemberOptions struct {
 	// Name is the name of the new cluster member that joined the cluster, triggering this hook.
 	Name string `json:"name" yaml:"name"`
+
+	// Member is the new cluster member that joined the cluster.
+	Member *ClusterMemberLocal `json:"member,omitempty" yaml:"member,omitempty"`
+}
+
+// HookEvent describes the event that caused a hook to run. It is passed as JSON on stdin to hook executables.
+type HookEvent struct {
+	Hook       HookType                 `json:"hook" yaml:"hook"`
+	Name       string                   `json:"name" yaml:"name"`
+	Address    string                   `json:"address" yaml:"address"`
+	Member     *ClusterMemberLocal      `json:"member,omitempty" yaml:"member,omitempty"`
+	Initiator  string                   `json:"initiator,omitempty" yaml:"initiator,omitempty"`
+	Force      bool                     `json:"force" yaml:"force"`
+	InitConfig map[string]string        `json:"init_config,omitempty" yaml:"init_config,omitempty"`
+	Heartbeat  map[string]ClusterMember `json:"heartbeat,omitempty" yaml:"heartbeat,omitempty"`
+	Source     string                   `json:"source,omitempty" yaml:"source,omitempty"`
+	Failures   int                      `json:"failures,omitempty" yaml:"failures,omitempty"`
 }
@@ -7,6 +7,6 @@ import (
 // Server represents server status information.
 type Server struct {
 	Name    string         `json:"name"    yaml:"name"`
-	Address types.AddrPort `json:"address" yaml:"address"`
+	Address types.HostPort `json:"address" yaml:"address"`
 	Ready   bool           `json:"ready"   yaml:"ready"`
 }
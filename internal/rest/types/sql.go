@@ -8,11 +8,25 @@ type SQLDump struct {
 // SQLQuery represents a SQL query.
 type SQLQuery struct {
 	Query string `json:"query" yaml:"query"`
+
+	// Transaction runs every statement of the query in a single transaction, so that none of them take effect if
+	// any fails. Otherwise each statement runs in its own transaction.
+	Transaction bool `json:"transaction" yaml:"transaction"`
+
+	// ReadOnly runs the query on a read-only connection, refusing any statement that would write to the database.
+	// It is enforced for unprivileged callers.
+	ReadOnly bool `json:"read_only" yaml:"read_only"`
+
+	// MaxRows is the maximum number of rows returned for each statement. Unlimited if zero.
+	MaxRows int `json:"max_rows" yaml:"max_rows"`
+
+	// Timeout is the number of seconds the query may run for. Defaults to 30 seconds if zero.
+	Timeout int `json:"timeout" yaml:"timeout"`
 }
 
 // SQLBatch represents a batch of SQL results.
 type SQLBatch struct {
-	Results []SQLResult
+	Results []SQLResult `json:"results" yaml:"results"`
 }
 
 // SQLResult represents the result of executing a SQL command.
@@ -21,4 +35,7 @@ type SQLResult struct {
 	Columns      []string `json:"columns" yaml:"columns"`
 	Rows         [][]any  `json:"rows" yaml:"rows"`
 	RowsAffected int64    `json:"rows_affected" yaml:"rows_affected"`
+
+	// Truncated is set if the statement returned more rows than the query's MaxRows.
+	Truncated bool `json:"truncated" yaml:"truncated"`
 }
@@ -0,0 +1,16 @@
+package types
+
+import (
+	"time"
+)
+
+// Task represents the status of a background task registered on a cluster member.
+type Task struct {
+	Name       string    `json:"name" yaml:"name"`
+	Schedule   string    `json:"schedule" yaml:"schedule"`
+	LeaderOnly bool      `json:"leader_only" yaml:"leader_only"`
+	Running    bool      `json:"running" yaml:"running"`
+	LastRun    time.Time `json:"last_run" yaml:"last_run"`
+	NextRun    time.Time `json:"next_run" yaml:"next_run"`
+	LastError  string    `json:"last_error" yaml:"last_error"`
+}
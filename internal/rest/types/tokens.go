@@ -44,7 +44,7 @@ type Token struct {
 
 	// JoinAddresses is the list of addresses of the existing cluster members that the joiner may supply the token to.
 	// Internally, the first system to accept the token will forward it to the dqlite leader.
-	JoinAddresses []types.AddrPort `json:"join_addresses" yaml:"join_addresses"`
+	JoinAddresses []types.HostPort `json:"join_addresses" yaml:"join_addresses"`
 }
 
 func (t Token) String() (string, error) {
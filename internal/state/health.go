@@ -0,0 +1,17 @@
+package state
+
+import (
+	"context"
+)
+
+// HealthCheck is an additional check reported as a component of the daemon health.
+type HealthCheck struct {
+	// Name of the component reported by the check.
+	Name string
+
+	// Critical determines whether a failure marks the daemon as unhealthy, rather than degraded.
+	Critical bool
+
+	// Check returns an error describing why the component is not healthy.
+	Check func(ctx context.Context, s *State) error
+}
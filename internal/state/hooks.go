@@ -0,0 +1,23 @@
+package state
+
+import (
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// HookContext describes the cluster event that caused a hook to run.
+type HookContext struct {
+	// Member is the cluster member the event concerns: the member that joined for OnNewMember, or the member being
+	// removed for PreRemove and PostRemove. It is empty for OnHeartbeat.
+	Member types.ClusterMemberLocal
+
+	// Initiator is the name of the cluster member that caused the hook to run, such as the leader removing a member
+	// or sending out a heartbeat, or the member that joined. It is empty if the initiator is running an older version.
+	Initiator string
+
+	// Force is set for PreRemove and PostRemove if the member is being forcibly removed.
+	Force bool
+
+	// Heartbeat holds the cluster members as of the end of a heartbeat round for OnHeartbeat, keyed by address.
+	// Members that could not be reached keep the time of their last successful heartbeat.
+	Heartbeat map[string]types.ClusterMember
+}
@@ -2,6 +2,8 @@ package state
 
 import (
 	"context"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/canonical/lxd/shared"
@@ -12,8 +14,12 @@ import (
 	"github.com/canonical/microcluster/internal/endpoints"
 	"github.com/canonical/microcluster/internal/extensions"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/microcluster/clock"
+	"github.com/canonical/microcluster/rest/types"
+	"github.com/canonical/microcluster/tasks"
 )
 
 // State is a gateway to the stateful components of the microcluster daemon.
@@ -21,6 +27,10 @@ type State struct {
 	// Context.
 	Context context.Context
 
+	// RequestContext is the context of the request that hooks are being run on behalf of, if any. Hooks are traced as
+	// part of the request, but are still stopped by the daemon's Context rather than when the request ends.
+	RequestContext context.Context
+
 	// Ready channel.
 	ReadyCh chan struct{}
 
@@ -33,12 +43,18 @@ type State struct {
 	// Listen Address.
 	Address func() *api.URL
 
+	// ResolvedAddress returns the IP addresses that the listen address resolved to when it was last configured.
+	ResolvedAddress func() types.AddrPorts
+
 	// Name of the cluster member.
 	Name func() string
 
 	// Server.
 	Endpoints *endpoints.Endpoints
 
+	// In-flight API requests.
+	Requests *endpoints.Requests
+
 	// Server certificate is used for server-to-server connection.
 	ServerCert func() *shared.CertInfo
 
@@ -51,33 +67,91 @@ type State struct {
 	// Remotes.
 	Remotes func() *trust.Remotes
 
+	// Tasks are run in the background on a schedule, on every cluster member or only on the leader.
+	Tasks func() *tasks.Scheduler
+
+	// IsLeader returns whether this member was the dqlite leader as of the last leadership check.
+	IsLeader func() bool
+
+	// Leadership returns whether this member is the dqlite leader, and a channel that is closed when that changes.
+	Leadership func() (bool, <-chan struct{})
+
+	// HookRuns returns the most recent runs of hooks on this cluster member, oldest first.
+	HookRuns func() []internalTypes.HookRun
+
+	// Dial establishes connections to other cluster members. Connections are made directly if unset.
+	Dial internalClient.DialFunc
+
+	// Clock tells the time for heartbeats, timeouts, and timestamps.
+	Clock clock.Clock
+
 	// Initialize APIs and bootstrap/join database.
 	StartAPI func(bootstrap bool, initConfig map[string]string, newConfig *trust.Location, joinAddresses ...string) error
 
+	// ConvertEmbedded moves an embedded database over to dqlite, so that other members can join.
+	ConvertEmbedded func() error
+
 	// Stop fully stops the daemon, its database, and all listeners.
 	Stop func() (exit func(), stopErr error)
 
 	// Runtime extensions.
 	Extensions extensions.Extensions
-}
 
-// StopListeners stops the network listeners and the fsnotify listener.
-var StopListeners func() error
+	// HealthChecks are reported alongside the built-in health checks.
+	HealthChecks []HealthCheck
+
+	// CheckConsistencyOnHeartbeat determines whether the leader logs any consistency issues it finds during each
+	// heartbeat.
+	CheckConsistencyOnHeartbeat bool
 
-// PostRemoveHook is a post-action hook that is run on all cluster members when a cluster member is removed.
-var PostRemoveHook func(state *State, force bool) error
+	// PendingMemberTimeout is how long a cluster member may remain pending without a dqlite node before the leader
+	// removes it.
+	PendingMemberTimeout time.Duration
 
-// PreRemoveHook is a post-action hook that is run on a cluster member just before it is is removed.
-var PreRemoveHook func(state *State, force bool) error
+	// JoinGuard tracks the failed join attempts received by this cluster member, to lock out those that keep failing.
+	JoinGuard *trust.JoinGuard
 
-// OnHeartbeatHook is a post-action hook that is run on the leader after a successful heartbeat round.
-var OnHeartbeatHook func(state *State) error
+	// Clients caches the client certificates trusted through the clients API.
+	Clients *trust.Clients
 
-// OnNewMemberHook is a post-action hook that is run on all cluster members when a new cluster member joins the cluster.
-var OnNewMemberHook func(state *State) error
+	// ClusterDisableMu prevents the daemon from being replaced or stopped during removal from the cluster until the
+	// request that initiated the removal has finished. This allows for self removal from the cluster when not the leader.
+	ClusterDisableMu *sync.Mutex
 
-// ReloadClusterCert reloads the cluster keypair from the state directory.
-var ReloadClusterCert func() error
+	// StopListeners stops the network listeners and the fsnotify listener.
+	StopListeners func() error
+
+	// ReExec replaces the daemon with a fresh one after its state has been reset.
+	ReExec func()
+
+	// ReloadClusterCert reloads the cluster keypair from the state directory.
+	ReloadClusterCert func() error
+
+	// PostRemoveHook is a post-action hook that is run on all cluster members when a cluster member is removed.
+	PostRemoveHook func(state *State, hookCtx HookContext) error
+
+	// PreRemoveHook is a post-action hook that is run on a cluster member just before it is is removed.
+	PreRemoveHook func(state *State, hookCtx HookContext) error
+
+	// OnHeartbeatHook is a post-action hook that is run on the leader after a successful heartbeat round.
+	OnHeartbeatHook func(state *State, hookCtx HookContext) error
+
+	// OnJoinRejectedHook is a post-action hook that is run on a cluster member after it rejects a join attempt with
+	// an invalid token.
+	OnJoinRejectedHook func(state *State, name string, source string, failures int) error
+
+	// OnNewMemberHook is a post-action hook that is run on all cluster members when a new cluster member joins the
+	// cluster.
+	OnNewMemberHook func(state *State, hookCtx HookContext) error
+}
+
+// ForRequest returns a copy of the state for running hooks on behalf of the given request.
+func (s *State) ForRequest(r *http.Request) *State {
+	requestState := *s
+	requestState.RequestContext = r.Context()
+
+	return &requestState
+}
 
 // Cluster returns a client for every member of a cluster, except
 // this one.
@@ -106,7 +180,7 @@ func (s *State) Cluster(isNotification bool) (client.Cluster, error) {
 		}
 
 		url := api.NewURL().Scheme("https").Host(clusterMember.Address.String())
-		c, err := internalClient.New(*url, s.ServerCert(), publicKey, isNotification)
+		c, err := internalClient.NewWithDialer(*url, s.ServerCert(), publicKey, isNotification, s.Dial)
 		if err != nil {
 			return nil, err
 		}
@@ -138,7 +212,7 @@ func (s *State) Leader() (*client.Client, error) {
 	}
 
 	url := api.NewURL().Scheme("https").Host(leaderInfo.Address)
-	c, err := internalClient.New(*url, s.ServerCert(), publicKey, false)
+	c, err := internalClient.NewWithDialer(*url, s.ServerCert(), publicKey, false, s.Dial)
 	if err != nil {
 		return nil, err
 	}
@@ -12,4 +12,7 @@ const (
 
 	// SocketGroup is the configurable group of the socket.
 	SocketGroup = "SOCKET_GROUP"
+
+	// LogFile is the configurable path of the daemon log file.
+	LogFile = "LOG_FILE"
 )
@@ -28,13 +28,11 @@ func DefaultOS(stateDir string, socketGroup string, createDir bool) (*OS, error)
 		socketGroup = os.Getenv(SocketGroup)
 	}
 
-	// TODO: Configurable log file path.
-
 	os := &OS{
 		StateDir:    stateDir,
 		DatabaseDir: filepath.Join(stateDir, "database"),
 		TrustDir:    filepath.Join(stateDir, "truststore"),
-		LogFile:     "",
+		LogFile:     os.Getenv(LogFile),
 		SocketGroup: socketGroup,
 	}
 
@@ -93,6 +91,16 @@ func (s *OS) DatabasePath() string {
 	return filepath.Join(s.DatabaseDir, "db.bin")
 }
 
+// EmbeddedDatabasePath returns the path of the local SQLite database used by a single member in embedded mode.
+func (s *OS) EmbeddedDatabasePath() string {
+	return filepath.Join(s.StateDir, "embedded.db")
+}
+
+// HookDir returns the directory of executables run for the hook of the given type, such as hooks/post-join.d.
+func (s *OS) HookDir(hookType string) string {
+	return filepath.Join(s.StateDir, "hooks", hookType+".d")
+}
+
 // ServerCert gets the local server certificate from the state directory.
 func (s *OS) ServerCert() (*shared.CertInfo, error) {
 	if !shared.PathExists(filepath.Join(s.StateDir, "server.crt")) {
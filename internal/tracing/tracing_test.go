@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testExporter keeps its spans when the tracer provider is shut down, so they can be checked after being flushed.
+type testExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (e testExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := testExporter{InMemoryExporter: tracetest.NewInMemoryExporter()}
+	shutdown := Init(exporter, "test")
+
+	// Send a request from one member.
+	ctx, clientSpan := Start(context.Background(), "client")
+	header := http.Header{}
+	Inject(ctx, header)
+	End(clientSpan, nil)
+
+	require.NotEmpty(t, header.Get("traceparent"))
+
+	// Receive it on another.
+	_, serverSpan := Start(Extract(context.Background(), header), "server")
+	End(serverSpan, errors.New("Failed"))
+
+	require.NoError(t, shutdown(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "client", spans[0].Name)
+	require.Equal(t, "server", spans[1].Name)
+	require.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	require.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	require.Equal(t, codes.Unset, spans[0].Status.Code)
+	require.Equal(t, codes.Error, spans[1].Status.Code)
+}
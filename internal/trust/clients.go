@@ -0,0 +1,53 @@
+package trust
+
+import (
+	"sync"
+	"time"
+)
+
+// clientsRefreshInterval is how long the trusted client certificates are cached before being loaded again. Clients
+// revoked through another cluster member are trusted by this one for at most this long.
+const clientsRefreshInterval = 10 * time.Second
+
+// Clients caches the fingerprints of the client certificates trusted through the clients API, so that requests with
+// an unknown certificate don't each need a database transaction.
+type Clients struct {
+	mu       sync.Mutex
+	names    map[string]string // Fingerprint to client name.
+	loadedAt time.Time
+}
+
+// NewClients returns an empty Clients cache, which is loaded on first use.
+func NewClients() *Clients {
+	return &Clients{}
+}
+
+// Lookup returns the name of the client trusted with the given certificate fingerprint, if any. The trusted clients
+// are loaded again with the given function if the cache is stale, which happens at most once per refresh interval.
+func (c *Clients) Lookup(fingerprint string, now time.Time, load func() (map[string]string, error)) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.names == nil || now.Sub(c.loadedAt) > clientsRefreshInterval {
+		names, err := load()
+		if err != nil {
+			return "", false, err
+		}
+
+		c.names = names
+		c.loadedAt = now
+	}
+
+	name, ok := c.names[fingerprint]
+
+	return name, ok, nil
+}
+
+// Invalidate forgets the cached clients, so they are loaded again on the next lookup. It is used when the trusted
+// clients are changed through this cluster member.
+func (c *Clients) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = nil
+}
@@ -0,0 +1,53 @@
+package trust
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClients(t *testing.T) {
+	clients := NewClients()
+	now := time.Now()
+
+	loads := 0
+	trusted := map[string]string{"fingerprint0": "client0"}
+	load := func() (map[string]string, error) {
+		loads++
+		names := map[string]string{}
+		for fingerprint, name := range trusted {
+			names[fingerprint] = name
+		}
+
+		return names, nil
+	}
+
+	name, ok, err := clients.Lookup("fingerprint0", now, load)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "client0", name)
+
+	// Unknown fingerprints don't load the clients again until the cache is stale.
+	for i := 0; i < 10; i++ {
+		_, ok, err = clients.Lookup("unknown", now.Add(time.Second), load)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+
+	require.Equal(t, 1, loads)
+
+	trusted["fingerprint1"] = "client1"
+	_, ok, err = clients.Lookup("fingerprint1", now.Add(clientsRefreshInterval+time.Second), load)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, loads)
+
+	// Invalidating the cache loads the clients again straight away.
+	delete(trusted, "fingerprint0")
+	clients.Invalidate()
+	_, ok, err = clients.Lookup("fingerprint0", now.Add(clientsRefreshInterval+time.Second), load)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 3, loads)
+}
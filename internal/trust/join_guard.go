@@ -0,0 +1,138 @@
+package trust
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// joinLockoutThreshold is the number of consecutive failed join attempts tolerated from a source address, or
+	// under a token name, before further attempts are locked out.
+	joinLockoutThreshold = 5
+
+	// joinLockoutBase is the lockout applied once the threshold is reached. It doubles with each further failure.
+	joinLockoutBase = time.Second
+
+	// joinLockoutMax is the longest lockout applied, and how long failures are remembered for a quiet source address
+	// or token name.
+	joinLockoutMax = 15 * time.Minute
+)
+
+// JoinGuard counts the failed join attempts received by this cluster member, both from each source address and under
+// the name of each token, so that sources which keep presenting invalid join tokens can be locked out. Failures are
+// only kept in memory, and are counted separately by each cluster member.
+type JoinGuard struct {
+	sources *failureCounter
+	names   *failureCounter
+}
+
+// NewJoinGuard returns a JoinGuard with no recorded failures.
+func NewJoinGuard() *JoinGuard {
+	return &JoinGuard{sources: newFailureCounter(), names: newFailureCounter()}
+}
+
+// JoinLockout returns how long further join attempts are refused after the given number of consecutive failures.
+func JoinLockout(failures int) time.Duration {
+	if failures < joinLockoutThreshold {
+		return 0
+	}
+
+	lockout := joinLockoutBase
+	for i := joinLockoutThreshold; i < failures && lockout < joinLockoutMax; i++ {
+		lockout *= 2
+	}
+
+	if lockout > joinLockoutMax {
+		return joinLockoutMax
+	}
+
+	return lockout
+}
+
+// LockedOut returns how long join attempts from the given source address must wait before being tried again.
+func (g *JoinGuard) LockedOut(source string, now time.Time) time.Duration {
+	return g.sources.lockedOut(source, now)
+}
+
+// Accept forgets the failed join attempts from the source address and under the name of the accepted token.
+func (g *JoinGuard) Accept(source string, name string) {
+	g.sources.reset(source)
+	g.names.reset(name)
+}
+
+// Reject records a join attempt from the source address with an invalid token. The attempt is also counted under the
+// requested name if a token exists with that name, so that guesses spread over many source addresses are locked out
+// too. It returns the number of consecutive failures from the source address, and how long attempts under the name
+// must wait before being tried again.
+func (g *JoinGuard) Reject(source string, name string, nameExists bool, now time.Time) (int, time.Duration) {
+	failures := g.sources.fail(source, now)
+
+	wait := g.names.lockedOut(name, now)
+	if wait == 0 && nameExists {
+		g.names.fail(name, now)
+	}
+
+	return failures, wait
+}
+
+// failureCounter counts consecutive failures by key.
+type failureCounter struct {
+	mu       sync.Mutex
+	failures map[string]*joinFailures
+}
+
+type joinFailures struct {
+	count       int
+	lockedUntil time.Time
+	updated     time.Time
+}
+
+func newFailureCounter() *failureCounter {
+	return &failureCounter{failures: map[string]*joinFailures{}}
+}
+
+// lockedOut returns how long attempts with the given key must wait before being tried again.
+func (c *failureCounter) lockedOut(key string, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failures, ok := c.failures[key]
+	if !ok || !now.Before(failures.lockedUntil) {
+		return 0
+	}
+
+	return failures.lockedUntil.Sub(now)
+}
+
+// fail records a failed attempt with the given key, and returns the number of consecutive failures.
+func (c *failureCounter) fail(key string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Forget about keys that have been quiet for long enough, so the map does not grow without bound.
+	for other, failures := range c.failures {
+		if now.Sub(failures.updated) > joinLockoutMax && !now.Before(failures.lockedUntil) {
+			delete(c.failures, other)
+		}
+	}
+
+	failures, ok := c.failures[key]
+	if !ok {
+		failures = &joinFailures{}
+		c.failures[key] = failures
+	}
+
+	failures.count++
+	failures.updated = now
+	failures.lockedUntil = now.Add(JoinLockout(failures.count))
+
+	return failures.count
+}
+
+// reset forgets the failed attempts with the given key.
+func (c *failureCounter) reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.failures, key)
+}
@@ -0,0 +1,72 @@
+package trust
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinLockout(t *testing.T) {
+	require.Equal(t, time.Duration(0), JoinLockout(joinLockoutThreshold-1))
+	require.Equal(t, joinLockoutBase, JoinLockout(joinLockoutThreshold))
+	require.Equal(t, 4*joinLockoutBase, JoinLockout(joinLockoutThreshold+2))
+	require.Equal(t, joinLockoutMax, JoinLockout(joinLockoutThreshold+100))
+}
+
+func TestJoinGuard(t *testing.T) {
+	guard := NewJoinGuard()
+	now := time.Now()
+
+	for i := 1; i < joinLockoutThreshold; i++ {
+		failures, wait := guard.Reject("10.0.0.1", "member1", false, now)
+		require.Equal(t, i, failures)
+		require.Equal(t, time.Duration(0), wait)
+		require.Equal(t, time.Duration(0), guard.LockedOut("10.0.0.1", now))
+	}
+
+	// Reaching the threshold locks the source out.
+	failures, _ := guard.Reject("10.0.0.1", "member1", false, now)
+	require.Equal(t, joinLockoutThreshold, failures)
+	require.Equal(t, joinLockoutBase, guard.LockedOut("10.0.0.1", now))
+	require.Equal(t, time.Duration(0), guard.LockedOut("10.0.0.2", now))
+
+	// Each further failure doubles the lockout.
+	now = now.Add(joinLockoutBase)
+	require.Equal(t, time.Duration(0), guard.LockedOut("10.0.0.1", now))
+	guard.Reject("10.0.0.1", "member1", false, now)
+	require.Equal(t, 2*joinLockoutBase, guard.LockedOut("10.0.0.1", now))
+
+	// Names without a token aren't counted.
+	require.Empty(t, guard.names.failures)
+
+	// Quiet sources are forgotten.
+	guard.Reject("10.0.0.2", "member1", false, now.Add(2*joinLockoutMax))
+	require.Len(t, guard.sources.failures, 1)
+
+	guard.Accept("10.0.0.2", "member1")
+	require.Empty(t, guard.sources.failures)
+}
+
+// Ensures guesses spread over many source addresses lock out further guesses under a token's name.
+func TestJoinGuardNames(t *testing.T) {
+	guard := NewJoinGuard()
+	now := time.Now()
+
+	var wait time.Duration
+	for i := 0; i < joinLockoutThreshold+1; i++ {
+		_, wait = guard.Reject(fmt.Sprintf("10.0.0.%d", i), "member1", true, now)
+	}
+
+	require.Equal(t, joinLockoutBase, wait)
+	require.Equal(t, joinLockoutThreshold, guard.names.failures["member1"].count)
+
+	// Attempts under a locked out name aren't counted against it again.
+	_, wait = guard.Reject("10.0.1.1", "member1", true, now)
+	require.Equal(t, joinLockoutBase, wait)
+	require.Equal(t, joinLockoutThreshold, guard.names.failures["member1"].count)
+
+	guard.Accept("10.0.2.1", "member1")
+	require.Empty(t, guard.names.failures)
+}
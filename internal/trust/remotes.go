@@ -1,6 +1,7 @@
 package trust
 
 import (
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -37,7 +39,7 @@ type Remote struct {
 // Location represents configurable identifying information about a remote.
 type Location struct {
 	Name    string         `yaml:"name"`
-	Address types.AddrPort `yaml:"address"`
+	Address types.HostPort `yaml:"address"`
 }
 
 // Load reads any yaml files in the given directory and parses them into a set of Remotes.
@@ -206,11 +208,11 @@ func (r *Remotes) SelectRandom() *Remote {
 }
 
 // Addresses returns just the host:port addresses of the remotes.
-func (r *Remotes) Addresses() map[string]types.AddrPort {
+func (r *Remotes) Addresses() map[string]types.HostPort {
 	r.updateMu.RLock()
 	defer r.updateMu.RUnlock()
 
-	addrs := map[string]types.AddrPort{}
+	addrs := map[string]types.HostPort{}
 	for _, remote := range r.data {
 		addrs[remote.Name] = remote.Address
 	}
@@ -219,11 +221,12 @@ func (r *Remotes) Addresses() map[string]types.AddrPort {
 }
 
 // Cluster returns a set of clients for every remote, which can be concurrently queried.
-func (r *Remotes) Cluster(isNotification bool, serverCert *shared.CertInfo, publicKey *x509.Certificate) (client.Cluster, error) {
+// Connections are established with the given dial function, if it is set.
+func (r *Remotes) Cluster(isNotification bool, serverCert *shared.CertInfo, publicKey *x509.Certificate, dial internalClient.DialFunc) (client.Cluster, error) {
 	cluster := make(client.Cluster, 0, r.Count()-1)
 	for _, addr := range r.Addresses() {
 		url := api.NewURL().Scheme("https").Host(addr.String())
-		c, err := internalClient.New(*url, serverCert, publicKey, isNotification)
+		c, err := internalClient.NewWithDialer(*url, serverCert, publicKey, isNotification, dial)
 		if err != nil {
 			return nil, err
 		}
@@ -235,12 +238,32 @@ func (r *Remotes) Cluster(isNotification bool, serverCert *shared.CertInfo, publ
 }
 
 // RemoteByAddress returns a Remote matching the given host address (or nil if none are found).
-func (r *Remotes) RemoteByAddress(addrPort types.AddrPort) *Remote {
-	r.updateMu.RLock()
-	defer r.updateMu.RUnlock()
+// Addresses are first compared directly, and only if there is no match are any DNS names resolved and compared by IP.
+func (r *Remotes) RemoteByAddress(hostPort types.HostPort) *Remote {
+	remotes := r.RemotesByName()
+	for _, remote := range remotes {
+		if remote.Address.Equal(hostPort) {
+			return &remote
+		}
+	}
 
-	for _, remote := range r.data {
-		if remote.Address.String() == addrPort.String() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, remote := range remotes {
+		if remote.Address.Matches(ctx, hostPort) {
+			return &remote
+		}
+	}
+
+	return nil
+}
+
+// RemoteByExactAddress returns a Remote whose address is Equal to the given host address (or nil if none are found).
+// Unlike RemoteByAddress, no DNS names are resolved, so the address may come from untrusted input.
+func (r *Remotes) RemoteByExactAddress(hostPort types.HostPort) *Remote {
+	for _, remote := range r.RemotesByName() {
+		if remote.Address.Equal(hostPort) {
 			return &remote
 		}
 	}
@@ -0,0 +1,35 @@
+package trust
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// Ensures addresses from untrusted input are only compared as given, without being resolved.
+func TestRemoteByExactAddress(t *testing.T) {
+	parse := func(address string) types.HostPort {
+		hostPort, err := types.ParseHostPort(address)
+		require.NoError(t, err)
+
+		return hostPort
+	}
+
+	remotes := &Remotes{data: map[string]Remote{
+		"member0": {Location: Location{Name: "member0", Address: parse("10.0.0.1:8443")}},
+		"member1": {Location: Location{Name: "member1", Address: parse("localhost:8443")}},
+	}}
+
+	remote := remotes.RemoteByExactAddress(parse("10.0.0.1:8443"))
+	require.NotNil(t, remote)
+	require.Equal(t, "member0", remote.Name)
+
+	remote = remotes.RemoteByExactAddress(parse("localhost:8443"))
+	require.NotNil(t, remote)
+	require.Equal(t, "member1", remote.Name)
+
+	require.Nil(t, remotes.RemoteByExactAddress(parse("10.0.0.1:8444")))
+	require.Nil(t, remotes.RemoteByExactAddress(parse("127.0.0.1:8443")))
+}
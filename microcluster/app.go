@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,18 +14,25 @@ import (
 	"time"
 
 	"github.com/canonical/lxd/lxd/db/schema"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"golang.org/x/sys/unix"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/config"
 	"github.com/canonical/microcluster/internal/daemon"
+	"github.com/canonical/microcluster/internal/logging"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/internal/tracing"
+	"github.com/canonical/microcluster/microcluster/clock"
+	"github.com/canonical/microcluster/microcluster/faults"
 	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
 	"github.com/canonical/microcluster/rest/types"
 )
 
@@ -47,11 +55,70 @@ type Args struct {
 	Proxy      func(*http.Request) (*url.URL, error)
 
 	ExtensionServers []rest.Server
+	HealthChecks     []rest.HealthCheck
+
+	// HeartbeatConsistency enables logging of any cluster consistency issues found by the leader during each heartbeat.
+	HeartbeatConsistency bool
+
+	// PendingMemberTimeout is how long a joining cluster member may remain pending before it is removed.
+	// Defaults to 10 minutes if unset.
+	PendingMemberTimeout time.Duration
+
+	// LogFile is a file to write logs to in addition to stderr. Defaults to the LOG_FILE environment variable.
+	LogFile string
+
+	// LogFormat is the format of log lines, one of "text", "json", or "journald". Defaults to "text".
+	LogFormat string
+
+	// LogMaxSize is the size in bytes after which the log file is rotated. The log file is not rotated if unset.
+	LogMaxSize int64
+
+	// LogMaxBackups is the number of rotated log files to keep.
+	LogMaxBackups int
+
+	// TraceExporter receives a span for each API request, database transaction, hook, and request sent to another
+	// cluster member. Takes precedence over TraceEndpoint. Tracing is disabled if neither is set.
+	TraceExporter sdktrace.SpanExporter
+
+	// TraceEndpoint is the URL of an OTLP/HTTP collector to send spans to, e.g. "http://localhost:4318".
+	TraceEndpoint string
+
+	// ClientCert is the keypair used by RemoteClient in place of the server certificate in the state directory, for
+	// clients outside of the cluster. The cluster must first trust it with TrustClient.
+	// The state directory may be omitted if ClientCert or Client is set, in which case the daemon can't be started.
+	ClientCert *shared.CertInfo
+
+	// ClusterCert verifies the certificate of the cluster members that RemoteClient connects to, in place of the
+	// cluster certificate in the state directory.
+	ClusterCert *x509.Certificate
+
+	// Authenticators identify the sender of requests that are not from a cluster member, a trusted client, or over
+	// the unix socket, e.g. with an access.BearerTokenAuthenticator or access.OIDCAuthenticator. They are consulted in
+	// order. Their identities may only read from endpoints using access.AllowAuthenticated, and are never allowed by
+	// access.AllowMember, so applications grant them more with their own access handlers.
+	Authenticators []access.Authenticator
+
+	// Faults injects network faults into the connections between the daemon and other cluster members, to test
+	// behaviour under partitions. For use in tests only.
+	Faults *faults.Injector
+
+	// Embedded runs a single cluster member on a local SQLite database, without dqlite replication or the cluster
+	// network listener. The database keeps the same schema, and is converted to dqlite when the first join token is
+	// issued, after which other members can join as normal.
+	Embedded bool
+
+	// Clock tells the time for the daemon's heartbeats and timeouts, and for polling in Ready. Defaults to the system
+	// clock. Tests may set a clock.Fake to control the passing of time.
+	Clock clock.Clock
 }
 
 // App returns an instance of MicroCluster with a newly initialized filesystem if one does not exist.
 func App(args Args) (*MicroCluster, error) {
 	if args.StateDir == "" {
+		if args.Client != nil || args.ClientCert != nil {
+			return &MicroCluster{args: args}, nil
+		}
+
 		return nil, fmt.Errorf("Missing state directory")
 	}
 	stateDir, err := filepath.Abs(args.StateDir)
@@ -63,6 +130,10 @@ func App(args Args) (*MicroCluster, error) {
 		return nil, err
 	}
 
+	if args.LogFile != "" {
+		os.LogFile = args.LogFile
+	}
+
 	return &MicroCluster{
 		FileSystem: os,
 		args:       args,
@@ -75,15 +146,50 @@ func App(args Args) (*MicroCluster, error) {
 // - `extensionsAPI` is a list of endpoints to be served over `/1.0`.
 // - `hooks` are a set of functions that trigger at certain points during cluster communication.
 func (m *MicroCluster) Start(ctx context.Context, extensionsAPI []rest.Endpoint, extensionsSchema []schema.Update, apiExtensions []string, hooks *config.Hooks) error {
+	if m.FileSystem == nil {
+		return fmt.Errorf("Missing state directory")
+	}
+
 	// Initialize the logger.
-	err := logger.InitLogger(m.FileSystem.LogFile, "", m.args.Verbose, m.args.Debug, nil)
+	err := logging.Init(logging.Config{
+		File:       m.FileSystem.LogFile,
+		MaxSize:    m.args.LogMaxSize,
+		MaxBackups: m.args.LogMaxBackups,
+		Format:     logging.Format(m.args.LogFormat),
+		Verbose:    m.args.Verbose,
+		Debug:      m.args.Debug,
+	})
 	if err != nil {
 		return err
 	}
 
+	// Initialize tracing.
+	exporter := m.args.TraceExporter
+	if exporter == nil && m.args.TraceEndpoint != "" {
+		exporter, err = tracing.NewOTLPExporter(ctx, m.args.TraceEndpoint)
+		if err != nil {
+			return fmt.Errorf("Failed to create trace exporter: %w", err)
+		}
+	}
+
+	if exporter != nil {
+		shutdownTracing := tracing.Init(exporter, filepath.Base(os.Args[0]))
+		defer func() {
+			err := shutdownTracing(context.Background())
+			if err != nil {
+				logger.Warn("Failed to flush traces", logger.Ctx{"error": err})
+			}
+		}()
+	}
+
 	// Start up a daemon with a basic control socket.
 	defer logger.Info("Daemon stopped")
 	d := daemon.NewDaemon(cluster.GetCallerProject())
+	d.Faults = m.args.Faults
+	d.Embedded = m.args.Embedded
+	if m.args.Clock != nil {
+		d.Clock = m.args.Clock
+	}
 
 	chIgnore := make(chan os.Signal, 1)
 	signal.Notify(chIgnore, unix.SIGHUP)
@@ -91,7 +197,7 @@ func (m *MicroCluster) Start(ctx context.Context, extensionsAPI []rest.Endpoint,
 	ctx, cancel := signal.NotifyContext(ctx, unix.SIGPWR, unix.SIGTERM, unix.SIGINT, unix.SIGQUIT)
 	defer cancel()
 
-	err = d.Run(ctx, m.args.ListenPort, m.FileSystem.StateDir, m.FileSystem.SocketGroup, extensionsAPI, extensionsSchema, apiExtensions, m.args.ExtensionServers, hooks)
+	err = d.Run(ctx, m.args.ListenPort, m.FileSystem.StateDir, m.FileSystem.SocketGroup, extensionsAPI, extensionsSchema, apiExtensions, m.args.ExtensionServers, m.args.HealthChecks, m.args.HeartbeatConsistency, m.args.PendingMemberTimeout, m.args.Authenticators, hooks)
 	if err != nil {
 		return fmt.Errorf("Daemon stopped with error: %w", err)
 	}
@@ -115,9 +221,54 @@ func (m *MicroCluster) Status(ctx context.Context) (*internalTypes.Server, error
 	return &server, nil
 }
 
+// Health returns the health of each component of the daemon.
+func (m *MicroCluster) Health(ctx context.Context) (*internalTypes.Health, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetHealth(ctx)
+}
+
+// Tasks returns the status of the background tasks registered on the daemon.
+func (m *MicroCluster) Tasks(ctx context.Context) ([]internalTypes.Task, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetTasks(ctx)
+}
+
+// TriggerTask runs the named background task as soon as possible, on the leader if it is a leader-only task.
+func (m *MicroCluster) TriggerTask(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.TriggerTask(ctx, name)
+}
+
+// HookRuns returns the most recent runs of hooks on the daemon, oldest first.
+func (m *MicroCluster) HookRuns(ctx context.Context) ([]internalTypes.HookRun, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetHookRuns(ctx)
+}
+
 // Ready waits for the daemon to report it has finished initial setup and is ready to be bootstrapped or join an
 // existing cluster.
 func (m *MicroCluster) Ready(ctx context.Context) error {
+	clk := m.args.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+
 	finger := make(chan error, 1)
 	var errLast error
 	go func() {
@@ -143,7 +294,7 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 					logger.Debugf("Failed connecting to MicroCluster daemon (attempt %d): %v", i, err)
 				}
 
-				time.Sleep(500 * time.Millisecond)
+				clk.Sleep(500 * time.Millisecond)
 				continue
 			}
 
@@ -158,7 +309,7 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 					logger.Debugf("Failed to check if MicroCluster daemon is ready (attempt %d): %v", i, err)
 				}
 
-				time.Sleep(500 * time.Millisecond)
+				clk.Sleep(500 * time.Millisecond)
 				continue
 			}
 
@@ -183,7 +334,7 @@ func (m *MicroCluster) NewCluster(ctx context.Context, name string, address stri
 		return err
 	}
 
-	addr, err := types.ParseAddrPort(address)
+	addr, err := types.ParseHostPort(address)
 	if err != nil {
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
@@ -198,7 +349,7 @@ func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address str
 		return err
 	}
 
-	addr, err := types.ParseAddrPort(address)
+	addr, err := types.ParseHostPort(address)
 	if err != nil {
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
@@ -257,6 +408,10 @@ func (m *MicroCluster) RevokeJoinToken(ctx context.Context, name string) error {
 func (m *MicroCluster) LocalClient() (*client.Client, error) {
 	c := m.args.Client
 	if c == nil {
+		if m.FileSystem == nil {
+			return nil, fmt.Errorf("Missing state directory")
+		}
+
 		internalClient, err := internalClient.New(m.FileSystem.ControlSocket(), nil, nil, false)
 		if err != nil {
 			return nil, err
@@ -279,26 +434,17 @@ func (m *MicroCluster) LocalClient() (*client.Client, error) {
 }
 
 // RemoteClient gets a client for the specified cluster member URL.
-// The filesystem will be parsed for the cluster and server certificates.
+// The filesystem will be parsed for the cluster and server certificates, unless ClientCert and ClusterCert are set.
 func (m *MicroCluster) RemoteClient(address string) (*client.Client, error) {
 	c := m.args.Client
 	if c == nil {
-		serverCert, err := m.FileSystem.ServerCert()
+		clientCert, publicKey, err := m.remoteCerts()
 		if err != nil {
 			return nil, err
 		}
 
-		var publicKey *x509.Certificate
-		clusterCert, err := m.FileSystem.ClusterCert()
-		if err == nil {
-			publicKey, err = clusterCert.PublicKeyX509()
-			if err != nil {
-				return nil, err
-			}
-		}
-
 		url := api.NewURL().Scheme("https").Host(address)
-		internalClient, err := internalClient.New(*url, serverCert, publicKey, false)
+		internalClient, err := internalClient.New(*url, clientCert, publicKey, false)
 		if err != nil {
 			return nil, err
 		}
@@ -319,8 +465,137 @@ func (m *MicroCluster) RemoteClient(address string) (*client.Client, error) {
 	return c, nil
 }
 
+// remoteCerts returns the keypair to authenticate with, and the certificate to verify cluster members with.
+func (m *MicroCluster) remoteCerts() (*shared.CertInfo, *x509.Certificate, error) {
+	clientCert := m.args.ClientCert
+	publicKey := m.args.ClusterCert
+	if m.FileSystem == nil {
+		if clientCert == nil {
+			return nil, nil, fmt.Errorf("Missing state directory")
+		}
+
+		return clientCert, publicKey, nil
+	}
+
+	if clientCert == nil {
+		serverCert, err := m.FileSystem.ServerCert()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clientCert = serverCert
+	}
+
+	if publicKey == nil {
+		clusterCert, err := m.FileSystem.ClusterCert()
+		if err == nil {
+			publicKey, err = clusterCert.PublicKeyX509()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return clientCert, publicKey, nil
+}
+
+// TrustClient exchanges a join token issued under the given name for the cluster's trust in the ClientCert, so that
+// RemoteClient can be used from outside of the cluster. The cluster member that accepted the token is verified against
+// the fingerprint in the token, and its address and the cluster certificate are returned for use with RemoteClient.
+func (m *MicroCluster) TrustClient(ctx context.Context, name string, token string) (string, *x509.Certificate, error) {
+	if m.args.ClientCert == nil {
+		return "", nil, fmt.Errorf("Missing client certificate")
+	}
+
+	clientCert, err := m.args.ClientCert.PublicKeyX509()
+	if err != nil {
+		return "", nil, err
+	}
+
+	joinToken, err := internalTypes.DecodeToken(token)
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid token: %w", err)
+	}
+
+	var lastErr error
+	for _, addr := range joinToken.JoinAddresses {
+		url := api.NewURL().Scheme("https").Host(addr.String())
+		cert, err := shared.GetRemoteCertificate(url.String(), "")
+		if err != nil {
+			lastErr = fmt.Errorf("Failed to get certificate of cluster member %q: %w", addr.String(), err)
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != joinToken.Fingerprint {
+			return "", nil, fmt.Errorf("Cluster certificate token does not match that of cluster member %q", addr.String())
+		}
+
+		c, err := internalClient.New(*url, m.args.ClientCert, cert, false)
+		if err != nil {
+			return "", nil, err
+		}
+
+		err = c.AddClientCertificate(ctx, internalTypes.ClientCertificatePost{
+			Name:        name,
+			Certificate: types.X509Certificate{Certificate: clientCert},
+			Secret:      joinToken.Secret,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		return addr.String(), cert, nil
+	}
+
+	return "", nil, fmt.Errorf("%d attempts to trust the client were unsuccessful. Last error: %w", len(joinToken.JoinAddresses), lastErr)
+}
+
+// ListClients lists the clients outside of the cluster that are trusted to use its API.
+func (m *MicroCluster) ListClients(ctx context.Context) ([]internalTypes.ClientCertificate, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetClientCertificates(ctx)
+}
+
+// RevokeClient revokes the cluster's trust in the client with the given name.
+func (m *MicroCluster) RevokeClient(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteClientCertificate(ctx, name)
+}
+
+// SQLOptions configures how the statements of a query are run by SQLWithOptions.
+type SQLOptions struct {
+	// Transaction runs every statement in a single transaction, so that none of them take effect if any fails.
+	Transaction bool
+
+	// ReadOnly refuses any statement that would write to the database.
+	ReadOnly bool
+
+	// MaxRows is the maximum number of rows returned for each statement. Unlimited if zero.
+	MaxRows int
+
+	// Timeout is how long the query may run for. Defaults to 30 seconds if zero.
+	Timeout time.Duration
+
+	// Target is the name of the cluster member to run the query on. Defaults to the local member.
+	Target string
+}
+
 // SQL performs either a GET or POST on /internal/sql with a given query. This is a useful helper for using direct SQL.
 func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internalTypes.SQLBatch, error) {
+	return m.SQLWithOptions(ctx, query, SQLOptions{})
+}
+
+// SQLWithOptions is like SQL, but runs any statements according to the given options.
+func (m *MicroCluster) SQLWithOptions(ctx context.Context, query string, opts SQLOptions) (string, *internalTypes.SQLBatch, error) {
 	if query == "-" {
 		// Read from stdin
 		bytes, err := io.ReadAll(os.Stdin)
@@ -336,6 +611,10 @@ func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internal
 		return "", nil, err
 	}
 
+	if opts.Target != "" {
+		c = c.UseTarget(opts.Target)
+	}
+
 	if query == ".dump" || query == ".schema" {
 		dump, err := c.GetSQL(ctx, query == ".schema")
 		if err != nil {
@@ -346,7 +625,11 @@ func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internal
 	}
 
 	data := internalTypes.SQLQuery{
-		Query: query,
+		Query:       query,
+		Transaction: opts.Transaction,
+		ReadOnly:    opts.ReadOnly,
+		MaxRows:     opts.MaxRows,
+		Timeout:     int(math.Ceil(opts.Timeout.Seconds())),
 	}
 
 	batch, err := c.PostSQL(ctx, data)
@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	require.Equal(t, start, c.Now())
+	require.Equal(t, time.Minute, c.Until(start.Add(time.Minute)))
+
+	// Non-positive durations fire immediately.
+	select {
+	case <-c.After(0):
+	default:
+		t.Fatal("Expected After(0) to fire immediately")
+	}
+
+	short := c.After(time.Second)
+	long := c.After(time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(30 * time.Second)
+		close(done)
+	}()
+
+	c.BlockUntil(3)
+
+	c.Advance(time.Second)
+	require.Equal(t, start.Add(time.Second), <-short)
+	require.Equal(t, time.Second, c.Since(start))
+
+	select {
+	case <-done:
+		t.Fatal("Sleep returned before its duration elapsed")
+	case <-long:
+		t.Fatal("After fired before its duration elapsed")
+	default:
+	}
+
+	c.Advance(time.Hour)
+	<-done
+	require.Equal(t, start.Add(time.Hour+time.Second), <-long)
+}
@@ -0,0 +1,293 @@
+// Package faults injects network faults between cluster members, to test how they behave under partitions.
+//
+// An Injector is passed to each daemon through microcluster.Args, and wraps every connection the daemon dials to, or
+// accepts from, another cluster member. Rules apply to the traffic sent from one member address to another, and can
+// be changed at any time, including for connections that are already established.
+//
+// Members are told apart on accepted connections by their IP address, so members sharing an Injector should each
+// listen on a distinct IP, e.g. 127.0.0.1, 127.0.0.2, etc.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Action is the fault applied to traffic matching a rule.
+type Action string
+
+const (
+	// Drop refuses new connections, and closes existing connections as soon as they send any data.
+	Drop Action = "drop"
+
+	// Delay holds each write, and each new connection, for the rule's Delay.
+	Delay Action = "delay"
+
+	// Blackhole silently discards traffic: new connections hang until their context is done, and writes on existing
+	// connections block until the rule is lifted or the connection is closed.
+	Blackhole Action = "blackhole"
+)
+
+// ErrDropped is returned for connections and writes refused by a Drop rule.
+var ErrDropped = errors.New("Connection dropped by fault injection")
+
+// Rule is a fault applied to the traffic sent from one member to another.
+type Rule struct {
+	Action Action
+	Delay  time.Duration
+}
+
+// pair is a directed pair of member addresses.
+type pair struct {
+	from string
+	to   string
+}
+
+// Injector applies faults to the connections between cluster members.
+type Injector struct {
+	mu      sync.Mutex
+	rules   map[pair]Rule
+	members map[string]bool // Addresses of the members that have dialed or listened through the injector.
+	changed chan struct{}   // Closed and replaced whenever the rules change.
+}
+
+// NewInjector returns an Injector with no rules, through which all traffic flows normally.
+func NewInjector() *Injector {
+	return &Injector{
+		rules:   map[pair]Rule{},
+		members: map[string]bool{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Set applies the rule to the traffic sent from the member at address `from` to the member at address `to`.
+func (i *Injector) Set(from string, to string, rule Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules[pair{from: from, to: to}] = rule
+	i.notify()
+}
+
+// Clear lifts any rule on the traffic sent from the member at address `from` to the member at address `to`.
+func (i *Injector) Clear(from string, to string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.rules, pair{from: from, to: to})
+	i.notify()
+}
+
+// Isolate applies the rule to all traffic in both directions between the member at the given address and every
+// other member known to the injector.
+func (i *Injector) Isolate(address string, rule Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for member := range i.members {
+		if member == address {
+			continue
+		}
+
+		i.rules[pair{from: address, to: member}] = rule
+		i.rules[pair{from: member, to: address}] = rule
+	}
+
+	i.notify()
+}
+
+// Reset lifts every rule.
+func (i *Injector) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = map[pair]Rule{}
+	i.notify()
+}
+
+// notify wakes up any writes waiting on a rule change. The caller must hold the lock.
+func (i *Injector) notify() {
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// rule returns the rule for the traffic sent from one address to another, and a channel closed when the rules next
+// change.
+func (i *Injector) rule(from string, to string) (Rule, bool, <-chan struct{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rule, ok := i.rules[pair{from: from, to: to}]
+
+	return rule, ok, i.changed
+}
+
+// register records the address of a member using the injector.
+func (i *Injector) register(address string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.members[address] = true
+}
+
+// member returns the address of the known member with the same host as the given address, or the address itself if
+// there is none. Connections accepted from a member come from an ephemeral port rather than its listen address.
+func (i *Injector) member(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for member := range i.members {
+		memberHost, _, err := net.SplitHostPort(member)
+		if err == nil && memberHost == host {
+			return member
+		}
+	}
+
+	return address
+}
+
+// wait applies the rule for the traffic sent from one address to another before a dial or write. It returns an error
+// if the traffic is dropped, or the context or connection is done while the traffic is blackholed.
+func (i *Injector) wait(ctx context.Context, done <-chan struct{}, from string, to string) error {
+	for {
+		rule, ok, changed := i.rule(from, to)
+		if !ok {
+			return nil
+		}
+
+		switch rule.Action {
+		case Drop:
+			return ErrDropped
+		case Delay:
+			select {
+			case <-time.After(rule.Delay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-done:
+				return net.ErrClosed
+			}
+
+		case Blackhole:
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-done:
+				return net.ErrClosed
+			}
+
+		default:
+			return fmt.Errorf("Unknown fault injection action %q", rule.Action)
+		}
+	}
+}
+
+// Dial connects the member at address `from` to the given address, applying the rules for traffic between them.
+// The connection is made from the IP of `from`, so that the receiving member can tell the members apart.
+func (i *Injector) Dial(ctx context.Context, from string, network string, address string) (net.Conn, error) {
+	i.register(from)
+
+	err := i.wait(ctx, nil, from, address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %q: %w", address, err)
+	}
+
+	dialer := &net.Dialer{}
+	host, _, err := net.SplitHostPort(from)
+	if err == nil {
+		ip := net.ParseIP(host)
+		if ip != nil && !ip.IsUnspecified() {
+			dialer.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(i, conn, from, address), nil
+}
+
+// Listen wraps the listener of the member at the given address, applying the rules for traffic between it and the
+// members that connect to it.
+func (i *Injector) Listen(address string, listener net.Listener) net.Listener {
+	i.register(address)
+
+	return &faultyListener{Listener: listener, injector: i, address: address}
+}
+
+type faultyListener struct {
+	net.Listener
+
+	injector *Injector
+	address  string
+}
+
+// Accept waits for the next connection that is not dropped or blackholed by the rules for its sender.
+func (l *faultyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		remote := l.injector.member(conn.RemoteAddr().String())
+		rule, ok, _ := l.injector.rule(remote, l.address)
+		if ok && (rule.Action == Drop || rule.Action == Blackhole) {
+			_ = conn.Close()
+			continue
+		}
+
+		return newConn(l.injector, conn, l.address, remote), nil
+	}
+}
+
+// faultyConn applies the rules for the traffic sent from its local member to its remote member on each write.
+// Each side of a connection between two members applies the rule for its own direction.
+type faultyConn struct {
+	net.Conn
+
+	injector *Injector
+	local    string
+	remote   string
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(injector *Injector, conn net.Conn, local string, remote string) *faultyConn {
+	return &faultyConn{Conn: conn, injector: injector, local: local, remote: remote, closed: make(chan struct{})}
+}
+
+// Write sends the data once the rules allow it, or closes the connection if it is dropped.
+func (c *faultyConn) Write(b []byte) (int, error) {
+	err := c.injector.wait(context.Background(), c.closed, c.local, c.remote)
+	if errors.Is(err, ErrDropped) {
+		_ = c.Close()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(b)
+}
+
+// Close closes the connection, and releases any blocked writes.
+func (c *faultyConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	return c.Conn.Close()
+}
@@ -0,0 +1,127 @@
+package faults
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer listens on the given address through the injector, and echoes back anything it receives.
+func echoServer(t *testing.T, i *Injector, address string) string {
+	l, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+
+	listener := i.Listen(l.Addr().String(), l)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// roundTrip writes to the connection and waits for the echo, returning an error if it takes longer than the timeout.
+func roundTrip(conn net.Conn, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Write([]byte("ping"))
+		if err == nil {
+			_, err = io.ReadFull(conn, make([]byte, 4))
+		}
+
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return context.DeadlineExceeded
+	}
+}
+
+func TestInjector(t *testing.T) {
+	i := NewInjector()
+	server := echoServer(t, i, "127.0.0.1:0")
+	client := "127.0.0.2:0"
+
+	ctx := context.Background()
+	conn, err := i.Dial(ctx, client, "tcp", server)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, roundTrip(conn, time.Second))
+
+	// Delays apply to existing connections.
+	i.Set(client, server, Rule{Action: Delay, Delay: 200 * time.Millisecond})
+	start := time.Now()
+	require.NoError(t, roundTrip(conn, time.Second))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	i.Clear(client, server)
+
+	// Blackholed writes resume once the rule is lifted.
+	i.Set(server, client, Rule{Action: Blackhole})
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, err = io.ReadFull(conn, make([]byte, 4))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	i.Reset()
+	require.NoError(t, conn.SetReadDeadline(time.Time{}))
+	_, err = io.ReadFull(conn, make([]byte, 4))
+	require.NoError(t, err)
+
+	// Blackholed dials hang until their context is done.
+	i.Set(client, server, Rule{Action: Blackhole})
+	dialCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = i.Dial(dialCtx, client, "tcp", server)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Dropped dials fail immediately, and existing connections are closed when they next write.
+	i.Set(client, server, Rule{Action: Drop})
+	_, err = i.Dial(ctx, client, "tcp", server)
+	require.ErrorIs(t, err, ErrDropped)
+	require.ErrorIs(t, roundTrip(conn, time.Second), ErrDropped)
+}
+
+func TestInjectorIsolate(t *testing.T) {
+	i := NewInjector()
+	serverA := echoServer(t, i, "127.0.0.1:0")
+	serverB := echoServer(t, i, "127.0.0.2:0")
+
+	ctx := context.Background()
+	i.Isolate(serverA, Rule{Action: Drop})
+
+	// Connections accepted from an isolated member are told apart by their IP, and closed.
+	conn, err := (&net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}}).DialContext(ctx, "tcp", serverB)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Error(t, roundTrip(conn, time.Second))
+
+	_, err = i.Dial(ctx, serverB, "tcp", serverA)
+	require.ErrorIs(t, err, ErrDropped)
+
+	i.Reset()
+	conn, err = i.Dial(ctx, serverB, "tcp", serverA)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, roundTrip(conn, time.Second))
+}
@@ -0,0 +1,540 @@
+// Package testcluster runs a multi-member MicroCluster inside a single process, for use in tests.
+//
+// Each member has its own temporary state directory and listens on an ephemeral loopback port. The cluster is
+// bootstrapped and joined through the same MicroCluster API as a real deployment, so extensions, schema updates, and
+// hooks behave as they would across several processes.
+package testcluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/schema"
+
+	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/config"
+	"github.com/canonical/microcluster/internal/daemon"
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/microcluster/faults"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+// Options configures the members of a test cluster.
+type Options struct {
+	// Members is the number of members to start. Defaults to 3.
+	Members int
+
+	// API is the list of endpoints served over `/1.0` by each member.
+	API []rest.Endpoint
+
+	// Schema is the list of schema updates applied by each member, in order.
+	Schema []schema.Update
+
+	// APIExtensions is the list of API extensions supported by each member.
+	APIExtensions []string
+
+	// Hooks are run by each member at certain points during cluster communication.
+	Hooks *config.Hooks
+
+	// ExtensionServers are additional servers managed by each member.
+	ExtensionServers []rest.Server
+
+	// HealthChecks are reported by each member alongside the built-in health checks.
+	HealthChecks []rest.HealthCheck
+
+	// Authenticators identify the sender of requests that are not from a cluster member.
+	Authenticators []access.Authenticator
+
+	// Faults injects network faults into the connections between members. Each member listens on its own loopback
+	// IP, so that the injector can tell them apart.
+	Faults *faults.Injector
+
+	// PendingMemberTimeout is how long a joining member may remain pending before it is removed.
+	PendingMemberTimeout time.Duration
+
+	// Timeout bounds how long each member may take to start, and how long Start waits for the cluster to form.
+	// Defaults to 2 minutes.
+	Timeout time.Duration
+}
+
+// Member is a single daemon of a test cluster.
+type Member struct {
+	// Name is the name the member joined the cluster with.
+	Name string
+
+	// Address is the loopback host and port the member listens on. Each member has its own loopback IP.
+	Address string
+
+	// StateDir is the member's state directory.
+	StateDir string
+
+	// App interacts with the member as a client would.
+	App *microcluster.MicroCluster
+
+	daemon *daemon.Daemon
+	cancel context.CancelFunc
+	doneCh chan error
+}
+
+// Running returns whether the member's daemon is running.
+func (m *Member) Running() bool {
+	return m.daemon != nil
+}
+
+// Cluster is a set of daemons running in the current process.
+type Cluster struct {
+	opts    Options
+	project string
+
+	mu      sync.Mutex
+	members []*Member
+	added   int // Number of members ever added, used to assign each a distinct loopback IP.
+}
+
+// Start starts the configured number of members, bootstraps the cluster on the first, and joins the rest to it.
+// The cluster is shut down and its state directories removed when the test completes.
+func Start(t testing.TB, opts Options) (*Cluster, error) {
+	if opts.Members <= 0 {
+		opts.Members = 3
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+
+	c := &Cluster{opts: opts, project: cluster.GetCallerProject()}
+	t.Cleanup(c.Shutdown)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	for i := 0; i < opts.Members; i++ {
+		_, err := c.Add(ctx, fmt.Sprintf("member%02d", i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.WaitForHeartbeat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Add starts a new member with the given name, and bootstraps a cluster on it if it is the first member, or joins it
+// to the cluster otherwise.
+func (c *Cluster) Add(ctx context.Context, name string) (*Member, error) {
+	if c.Member(name) != nil {
+		return nil, fmt.Errorf("Member %q already exists", name)
+	}
+
+	c.mu.Lock()
+	c.added++
+	ip := fmt.Sprintf("127.0.%d.%d", c.added/250, c.added%250+1)
+	c.mu.Unlock()
+
+	address, err := freeAddress(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	// The control socket path must fit in a sockaddr_un, so avoid the long directories of testing.T.TempDir.
+	stateDir, err := os.MkdirTemp("", "microcluster-")
+	if err != nil {
+		return nil, err
+	}
+
+	member := &Member{Name: name, Address: address, StateDir: stateDir}
+	err = c.start(member)
+	if err != nil {
+		_ = os.RemoveAll(stateDir)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	existing := c.running()
+	c.members = append(c.members, member)
+	c.mu.Unlock()
+
+	if len(existing) == 0 {
+		err = member.App.NewCluster(ctx, name, address, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to bootstrap member %q: %w", name, err)
+		}
+
+		return member, nil
+	}
+
+	token, err := existing[0].App.NewJoinToken(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to issue join token for member %q: %w", name, err)
+	}
+
+	err = member.App.JoinCluster(ctx, name, address, token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to join member %q: %w", name, err)
+	}
+
+	return member, nil
+}
+
+// start runs a daemon from the member's state directory, and waits for it to be ready.
+func (c *Cluster) start(member *Member) error {
+	app, err := microcluster.App(microcluster.Args{StateDir: member.StateDir})
+	if err != nil {
+		return err
+	}
+
+	d := daemon.NewDaemon(c.project)
+	d.Faults = c.opts.Faults
+
+	// A removed member's daemon is stopped, rather than re-executing the whole process.
+	d.ReExec = func() {
+		go func() { _ = c.Stop(member.Name) }()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- d.Run(ctx, "", member.StateDir, "", c.opts.API, c.opts.Schema, c.opts.APIExtensions, c.opts.ExtensionServers, c.opts.HealthChecks, false, c.opts.PendingMemberTimeout, c.opts.Authenticators, c.opts.Hooks)
+	}()
+
+	select {
+	case <-d.ReadyChan:
+	case err := <-doneCh:
+		cancel()
+		return fmt.Errorf("Member %q failed to start: %w", member.Name, err)
+	case <-time.After(c.opts.Timeout):
+		cancel()
+		<-doneCh
+		return fmt.Errorf("Member %q still not ready after %s", member.Name, c.opts.Timeout)
+	}
+
+	c.mu.Lock()
+	member.App = app
+	member.daemon = d
+	member.cancel = cancel
+	member.doneCh = doneCh
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Members returns all members of the cluster, including those that are stopped.
+func (c *Cluster) Members() []*Member {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Member{}, c.members...)
+}
+
+// Member returns the member with the given name, or nil if there is none.
+func (c *Cluster) Member(name string) *Member {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, member := range c.members {
+		if member.Name == name {
+			return member
+		}
+	}
+
+	return nil
+}
+
+// running returns the members whose daemon is running. The caller must hold the lock.
+func (c *Cluster) running() []*Member {
+	members := []*Member{}
+	for _, member := range c.members {
+		if member.Running() {
+			members = append(members, member)
+		}
+	}
+
+	return members
+}
+
+// stop stops the named member's daemon, either gracefully or as if it crashed.
+func (c *Cluster) stop(name string, kill bool) error {
+	c.mu.Lock()
+	var member *Member
+	for _, m := range c.members {
+		if m.Name == name {
+			member = m
+		}
+	}
+
+	if member == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("No member found with name %q", name)
+	}
+
+	d, cancel, doneCh := member.daemon, member.cancel, member.doneCh
+	member.daemon, member.cancel, member.doneCh = nil, nil, nil
+	c.mu.Unlock()
+
+	if d == nil {
+		return nil
+	}
+
+	var killErr error
+	if kill {
+		killErr = d.Kill()
+	}
+
+	cancel()
+	err := <-doneCh
+	if killErr != nil {
+		return fmt.Errorf("Failed to kill member %q: %w", name, killErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Member %q stopped with error: %w", name, err)
+	}
+
+	return nil
+}
+
+// Stop gracefully stops the named member, handing over dqlite leadership and running its pre-shutdown hook.
+// Its state directory is kept, so it can be started again with Restart.
+func (c *Cluster) Stop(name string) error {
+	return c.stop(name, false)
+}
+
+// Kill stops the named member as if its process had crashed.
+// Its state directory is kept, so it can be started again with Restart.
+func (c *Cluster) Kill(name string) error {
+	return c.stop(name, true)
+}
+
+// Restart stops the named member if it is running, and starts it again from its state directory.
+func (c *Cluster) Restart(name string) error {
+	member := c.Member(name)
+	if member == nil {
+		return fmt.Errorf("No member found with name %q", name)
+	}
+
+	err := c.Stop(name)
+	if err != nil {
+		return err
+	}
+
+	return c.start(member)
+}
+
+// Remove removes the named member from the cluster through another running member, stops it, and deletes its
+// state directory.
+func (c *Cluster) Remove(ctx context.Context, name string, force bool) error {
+	member := c.Member(name)
+	if member == nil {
+		return fmt.Errorf("No member found with name %q", name)
+	}
+
+	var peer *Member
+	for _, m := range c.Members() {
+		if m.Name != name && m.Running() {
+			peer = m
+			break
+		}
+	}
+
+	if peer == nil {
+		return fmt.Errorf("No other running member to remove %q through", name)
+	}
+
+	peerClient, err := peer.App.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = peerClient.DeleteClusterMember(ctx, name, force)
+	if err != nil {
+		return fmt.Errorf("Failed to remove member %q: %w", name, err)
+	}
+
+	// The removed member has already closed its database and listeners, so errors from stopping it are expected.
+	_ = c.Stop(name)
+
+	c.mu.Lock()
+	for i, m := range c.members {
+		if m == member {
+			c.members = append(c.members[:i], c.members[i+1:]...)
+			break
+		}
+	}
+
+	c.mu.Unlock()
+
+	return os.RemoveAll(member.StateDir)
+}
+
+// Shutdown stops every member and removes their state directories.
+func (c *Cluster) Shutdown() {
+	for _, member := range c.Members() {
+		_ = c.Stop(member.Name)
+		_ = os.RemoveAll(member.StateDir)
+	}
+
+	c.mu.Lock()
+	c.members = nil
+	c.mu.Unlock()
+}
+
+// Client returns a client connected to the named member's control socket.
+func (c *Cluster) Client(name string) (*client.Client, error) {
+	member := c.Member(name)
+	if member == nil {
+		return nil, fmt.Errorf("No member found with name %q", name)
+	}
+
+	return member.App.LocalClient()
+}
+
+// RemoteClient returns a client connected to the named member's cluster address, authenticated with the member's own
+// server certificate.
+func (c *Cluster) RemoteClient(name string) (*client.Client, error) {
+	member := c.Member(name)
+	if member == nil {
+		return nil, fmt.Errorf("No member found with name %q", name)
+	}
+
+	return member.App.RemoteClient(member.Address)
+}
+
+// Leader returns the member that is currently the dqlite leader, as seen by the first running member.
+func (c *Cluster) Leader(ctx context.Context) (*Member, error) {
+	c.mu.Lock()
+	running := c.running()
+	var d *daemon.Daemon
+	if len(running) > 0 {
+		d = running[0].daemon
+	}
+
+	c.mu.Unlock()
+
+	if d == nil {
+		return nil, fmt.Errorf("No members are running")
+	}
+
+	leaderClient, err := d.State().Database.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer leaderClient.Close()
+
+	info, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("No leader elected")
+	}
+
+	for _, member := range running {
+		if member.Address == info.Address {
+			return member, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Leader %q is not a running member", info.Address)
+}
+
+// WaitForLeader waits until a running member is the dqlite leader, and returns it.
+func (c *Cluster) WaitForLeader(ctx context.Context) (*Member, error) {
+	var leader *Member
+	err := poll(ctx, func(ctx context.Context) error {
+		var err error
+		leader, err = c.Leader(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("No leader elected: %w", err)
+	}
+
+	return leader, nil
+}
+
+// WaitForHeartbeat waits until every running member sees every other running member as an online, non-pending
+// member of the cluster.
+func (c *Cluster) WaitForHeartbeat(ctx context.Context) error {
+	err := poll(ctx, func(ctx context.Context) error {
+		c.mu.Lock()
+		running := c.running()
+		c.mu.Unlock()
+
+		for _, member := range running {
+			memberClient, err := member.App.LocalClient()
+			if err != nil {
+				return err
+			}
+
+			clusterMembers, err := memberClient.GetClusterMembers(ctx)
+			if err != nil {
+				return fmt.Errorf("Failed to list members from %q: %w", member.Name, err)
+			}
+
+			seen := make(map[string]types.ClusterMember, len(clusterMembers))
+			for _, clusterMember := range clusterMembers {
+				seen[clusterMember.Name] = clusterMember
+			}
+
+			for _, other := range running {
+				clusterMember, ok := seen[other.Name]
+				if !ok {
+					return fmt.Errorf("Member %q does not see %q", member.Name, other.Name)
+				}
+
+				if clusterMember.Role == string(cluster.Pending) || clusterMember.Status != types.MemberOnline {
+					return fmt.Errorf("Member %q sees %q as %s with status %s", member.Name, other.Name, clusterMember.Role, clusterMember.Status)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Cluster did not converge: %w", err)
+	}
+
+	return nil
+}
+
+// poll runs f every half second until it succeeds or the context is done, returning the last error.
+func poll(ctx context.Context, f func(ctx context.Context) error) error {
+	for {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// freeAddress returns an address on the given IP with a port that is free at the time of the call.
+func freeAddress(ip string) (string, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return "", fmt.Errorf("Failed to find a free port: %w", err)
+	}
+
+	defer l.Close()
+
+	return l.Addr().String(), nil
+}
@@ -0,0 +1,111 @@
+package testcluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/microcluster/faults"
+)
+
+// TestCluster needs a working dqlite, so it is only run with `make check-system`.
+func TestCluster(t *testing.T) {
+	if os.Getenv("MICROCLUSTER_SYSTEM_TESTS") == "" {
+		t.Skip("MICROCLUSTER_SYSTEM_TESTS is not set")
+	}
+
+	c, err := Start(t, Options{Members: 3})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	leader, err := c.WaitForLeader(ctx)
+	require.NoError(t, err)
+
+	// Stopping the leader hands leadership over to another member.
+	require.NoError(t, c.Stop(leader.Name))
+	newLeader, err := c.WaitForLeader(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, leader.Name, newLeader.Name)
+
+	require.NoError(t, c.Restart(leader.Name))
+	require.NoError(t, c.WaitForHeartbeat(ctx))
+
+	// A killed member can be brought back from its state directory.
+	require.NoError(t, c.Kill(newLeader.Name))
+	_, err = c.WaitForLeader(ctx)
+	require.NoError(t, err)
+	require.NoError(t, c.Restart(newLeader.Name))
+	require.NoError(t, c.WaitForHeartbeat(ctx))
+
+	for _, member := range c.Members() {
+		client, err := c.Client(member.Name)
+		require.NoError(t, err)
+
+		members, err := client.GetClusterMembers(ctx)
+		require.NoError(t, err)
+		require.Len(t, members, 3)
+	}
+
+	require.NoError(t, c.Remove(ctx, leader.Name, false))
+	require.NoError(t, c.WaitForHeartbeat(ctx))
+	require.Len(t, c.Members(), 2)
+
+	client, err := c.RemoteClient(c.Members()[0].Name)
+	require.NoError(t, err)
+
+	members, err := client.GetClusterMembers(ctx)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+}
+
+// TestClusterLeaderIsolated needs a working dqlite, so it is only run with `make check-system`.
+func TestClusterLeaderIsolated(t *testing.T) {
+	if os.Getenv("MICROCLUSTER_SYSTEM_TESTS") == "" {
+		t.Skip("MICROCLUSTER_SYSTEM_TESTS is not set")
+	}
+
+	injector := faults.NewInjector()
+	c, err := Start(t, Options{Members: 3, Faults: injector})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	leader, err := c.WaitForLeader(ctx)
+	require.NoError(t, err)
+
+	// The remaining members elect a new leader once the leader is cut off from them.
+	injector.Isolate(leader.Address, faults.Rule{Action: faults.Blackhole})
+
+	// Ask the members on the majority side, as the isolated member can't reach the new leader.
+	err = poll(ctx, func(ctx context.Context) error {
+		for _, member := range c.Members() {
+			if member.Name == leader.Name {
+				continue
+			}
+
+			leaderClient, err := member.daemon.State().Database.Leader(ctx)
+			if err != nil {
+				continue
+			}
+
+			info, err := leaderClient.Leader(ctx)
+			_ = leaderClient.Close()
+			if err == nil && info != nil && info.Address != leader.Address {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("No new leader elected after isolating %q", leader.Name)
+	})
+	require.NoError(t, err)
+
+	injector.Reset()
+	require.NoError(t, c.WaitForHeartbeat(ctx))
+}
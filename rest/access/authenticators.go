@@ -0,0 +1,377 @@
+package access
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/endpoints"
+	"github.com/canonical/microcluster/internal/rest/access"
+	"github.com/canonical/microcluster/internal/state"
+)
+
+// Identity describes the authenticated sender of a request.
+type Identity = access.Identity
+
+// PeerCredentials identifies the process that sent a request over the unix socket.
+type PeerCredentials = endpoints.PeerCredentials
+
+// Authentication methods by which an Identity can be established.
+const (
+	MethodUnix   = access.MethodUnix
+	MethodTLS    = access.MethodTLS
+	MethodClient = access.MethodClient
+	MethodBearer = access.MethodBearer
+	MethodOIDC   = access.MethodOIDC
+)
+
+// Authenticator establishes the identity of the sender of a request from the credentials it carries.
+type Authenticator interface {
+	// Authenticate returns the identity of the request's sender, or nil if the request carries no credentials that
+	// the authenticator recognizes. An error is returned if the credentials are recognized but invalid.
+	Authenticate(state *state.State, r *http.Request) (*Identity, error)
+}
+
+// RequestIdentity returns the identity of the request's sender, or nil if the request is untrusted.
+func RequestIdentity(r *http.Request) *Identity {
+	return access.GetRequestIdentity(r)
+}
+
+// TLSAuthenticator authenticates requests over the unix socket, and requests with a TLS client certificate found in
+// the truststore. It is always consulted first, so that cluster members can authenticate with each other.
+type TLSAuthenticator struct{}
+
+// Authenticate identifies cluster members by the name recorded in the truststore for their certificate.
+func (TLSAuthenticator) Authenticate(state *state.State, r *http.Request) (*Identity, error) {
+	trusted, err := Authenticate(state, r, state.Address().URL.Host, state.Remotes().CertificatesNative())
+	if err != nil || !trusted {
+		return nil, err
+	}
+
+	if r.RemoteAddr == "@" {
+		identity := &Identity{Method: MethodUnix, Credentials: endpoints.GetPeerCredentials(r.Context())}
+		if identity.Credentials != nil {
+			identity.Username = strconv.FormatUint(uint64(identity.Credentials.UID), 10)
+			u, err := user.LookupId(identity.Username)
+			if err == nil {
+				identity.Username = u.Username
+			}
+		}
+
+		return identity, nil
+	}
+
+	identity := &Identity{Method: MethodTLS}
+	if r.TLS != nil {
+		for _, cert := range r.TLS.PeerCertificates {
+			remote := state.Remotes().RemoteByCertificateFingerprint(shared.CertFingerprint(cert))
+			if remote != nil {
+				identity.Username = remote.Name
+				break
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// ClientCertificateAuthenticator authenticates requests with a TLS client certificate that was trusted in exchange for
+// a join token, for clients that are not cluster members. It is always consulted after the TLSAuthenticator.
+type ClientCertificateAuthenticator struct{}
+
+// Authenticate identifies clients by the name their certificate was trusted under. The trusted certificates are
+// cached in memory, so that unknown certificates don't each cost a database transaction.
+func (ClientCertificateAuthenticator) Authenticate(state *state.State, r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 || state.Clients == nil || state.Database == nil || !state.Database.IsOpen() {
+		return nil, nil
+	}
+
+	load := func() (map[string]string, error) {
+		names := map[string]string{}
+		err := state.Database.Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+			clients, err := cluster.GetInternalClientCertificates(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			for _, client := range clients {
+				names[client.Fingerprint] = client.Name
+			}
+
+			return nil
+		})
+
+		return names, err
+	}
+
+	for _, cert := range r.TLS.PeerCertificates {
+		name, ok, err := state.Clients.Lookup(shared.CertFingerprint(cert), state.Clock.Now(), load)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to check client certificates: %w", err)
+		}
+
+		if ok {
+			return &Identity{Username: name, Method: MethodClient}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// bearerToken returns the token from the request's Authorization header, if it has one.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// BearerTokenAuthenticator authenticates requests with one of a set of static tokens in the Authorization header.
+type BearerTokenAuthenticator struct {
+	// Tokens maps each accepted token to the identity of its holder.
+	Tokens map[string]Identity
+}
+
+// Authenticate compares the request's bearer token against each accepted token in constant time.
+// Unknown tokens are left for other authenticators, as they may be OIDC tokens.
+func (a BearerTokenAuthenticator) Authenticate(state *state.State, r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+
+	var match *Identity
+	for accepted, identity := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(accepted), []byte(token)) == 1 {
+			match = &Identity{Username: identity.Username, Groups: identity.Groups, Method: MethodBearer}
+		}
+	}
+
+	return match, nil
+}
+
+// jwksRefreshInterval is how long the keys of an OIDC issuer are cached before being loaded again.
+const jwksRefreshInterval = 5 * time.Minute
+
+// jwksRetryInterval is the minimum time between loading the keys of an OIDC issuer when a token is signed by an
+// unknown key, so that tokens with made up key IDs can't be used to hammer the issuer.
+const jwksRetryInterval = 10 * time.Second
+
+// OIDCConfig configures an OIDCAuthenticator.
+type OIDCConfig struct {
+	// Issuer must match the "iss" claim of accepted tokens.
+	Issuer string
+
+	// Audience, if set, must be one of the "aud" claims of accepted tokens.
+	Audience string
+
+	// JWKS is the URL or file path of the JSON Web Key Set used to verify the signature of tokens.
+	JWKS string
+
+	// UsernameClaim is the claim holding the username. Defaults to "sub".
+	UsernameClaim string
+
+	// GroupsClaim is the claim holding the list of groups. Defaults to "groups".
+	GroupsClaim string
+}
+
+// OIDCAuthenticator authenticates requests with an OpenID Connect JWT in the Authorization header, signed by one of
+// the keys of the configured issuer.
+type OIDCAuthenticator struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	keys     *jose.JSONWebKeySet
+	loadedAt time.Time
+	loading  bool // Whether a request is loading the key set.
+}
+
+// NewOIDCAuthenticator returns an OIDCAuthenticator with the given configuration.
+func NewOIDCAuthenticator(config OIDCConfig) *OIDCAuthenticator {
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "sub"
+	}
+
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	return &OIDCAuthenticator{config: config}
+}
+
+// Authenticate verifies the signature and claims of the request's bearer token, if it was issued by the configured
+// issuer. Tokens that are not JWTs, or are issued by someone else, are left for other authenticators.
+func (a *OIDCAuthenticator) Authenticate(state *state.State, r *http.Request) (*Identity, error) {
+	token, err := jwt.ParseSigned(bearerToken(r))
+	if err != nil {
+		return nil, nil
+	}
+
+	var unverified jwt.Claims
+	err = token.UnsafeClaimsWithoutVerification(&unverified)
+	if err != nil || unverified.Issuer != a.config.Issuer {
+		return nil, nil
+	}
+
+	var keyID string
+	for _, header := range token.Headers {
+		if header.KeyID != "" {
+			keyID = header.KeyID
+			break
+		}
+	}
+
+	key, err := a.key(r.Context(), keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	extra := map[string]any{}
+	err = token.Claims(key, &claims, &extra)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OIDC token signature: %w", err)
+	}
+
+	expected := jwt.Expected{Issuer: a.config.Issuer, Time: time.Now()}
+	if a.config.Audience != "" {
+		expected.Audience = jwt.Audience{a.config.Audience}
+	}
+
+	err = claims.ValidateWithLeeway(expected, jwt.DefaultLeeway)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OIDC token: %w", err)
+	}
+
+	username, _ := extra[a.config.UsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("OIDC token is missing the %q claim", a.config.UsernameClaim)
+	}
+
+	identity := &Identity{Username: username, Method: MethodOIDC}
+	groups, _ := extra[a.config.GroupsClaim].([]any)
+	for _, group := range groups {
+		name, ok := group.(string)
+		if ok {
+			identity.Groups = append(identity.Groups, name)
+		}
+	}
+
+	return identity, nil
+}
+
+// key returns the issuer's key with the given ID, loading the key set again if it is stale or lacks the key. The key
+// set is loaded without holding the lock, so that a slow issuer doesn't block requests that can use the cached keys.
+func (a *OIDCAuthenticator) key(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	find := func(keys *jose.JSONWebKeySet) *jose.JSONWebKey {
+		if keys == nil {
+			return nil
+		}
+
+		for _, key := range keys.Keys {
+			if key.Use != "enc" && (keyID == "" || key.KeyID == keyID) {
+				return &key
+			}
+		}
+
+		return nil
+	}
+
+	a.mu.Lock()
+	since := time.Since(a.loadedAt)
+	key := find(a.keys)
+	stale := since > jwksRefreshInterval || (key == nil && since > jwksRetryInterval)
+
+	// Stale keys are still used while another request is loading them again.
+	if !stale || (a.loading && key != nil) {
+		a.mu.Unlock()
+		if key == nil {
+			return nil, fmt.Errorf("OIDC token is signed by unknown key %q", keyID)
+		}
+
+		return key, nil
+	}
+
+	a.loading = true
+	a.mu.Unlock()
+
+	keys, err := loadJWKS(ctx, a.config.JWKS)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.loading = false
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load OIDC keys from %q: %w", a.config.JWKS, err)
+	}
+
+	a.keys = keys
+	a.loadedAt = time.Now()
+	key = find(keys)
+	if key == nil {
+		return nil, fmt.Errorf("OIDC token is signed by unknown key %q", keyID)
+	}
+
+	return key, nil
+}
+
+// loadJWKS reads a JSON Web Key Set from the given URL or file path.
+func loadJWKS(ctx context.Context, source string) (*jose.JSONWebKeySet, error) {
+	var data []byte
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Unexpected status %q", resp.Status)
+		}
+
+		data, err = io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := &jose.JSONWebKeySet{}
+	err := json.Unmarshal(data, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,148 @@
+package access
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// stubIssuer signs tokens and serves its key set over HTTP, like an OpenID Connect provider.
+type stubIssuer struct {
+	server *httptest.Server
+	signer jose.Signer
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "test"}}, nil)
+	require.NoError(t, err)
+
+	keys := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(keys)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return &stubIssuer{server: server, signer: signer}
+}
+
+func (i *stubIssuer) token(t *testing.T, claims jwt.Claims, extra map[string]any) string {
+	token, err := jwt.Signed(i.signer).Claims(claims).Claims(extra).CompactSerialize()
+	require.NoError(t, err)
+
+	return token
+}
+
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest("GET", "/1.0", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+func TestBearerTokenAuthenticator(t *testing.T) {
+	authenticator := BearerTokenAuthenticator{Tokens: map[string]Identity{"secret": {Username: "admin", Groups: []string{"admins"}}}}
+
+	identity, err := authenticator.Authenticate(nil, requestWithToken("secret"))
+	require.NoError(t, err)
+	require.Equal(t, &Identity{Username: "admin", Groups: []string{"admins"}, Method: MethodBearer}, identity)
+
+	identity, err = authenticator.Authenticate(nil, requestWithToken("wrong"))
+	require.NoError(t, err)
+	require.Nil(t, identity)
+
+	identity, err = authenticator.Authenticate(nil, httptest.NewRequest("GET", "/1.0", nil))
+	require.NoError(t, err)
+	require.Nil(t, identity)
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	issuer := newStubIssuer(t)
+	authenticator := NewOIDCAuthenticator(OIDCConfig{Issuer: "https://issuer.test", Audience: "microcluster", JWKS: issuer.server.URL})
+
+	now := time.Now()
+	claims := jwt.Claims{
+		Issuer:   "https://issuer.test",
+		Subject:  "user",
+		Audience: jwt.Audience{"microcluster"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+
+	token := issuer.token(t, claims, map[string]any{"groups": []string{"admins"}})
+	identity, err := authenticator.Authenticate(nil, requestWithToken(token))
+	require.NoError(t, err)
+	require.Equal(t, &Identity{Username: "user", Groups: []string{"admins"}, Method: MethodOIDC}, identity)
+
+	// Expired tokens are rejected.
+	expired := claims
+	expired.Expiry = jwt.NewNumericDate(now.Add(-time.Hour))
+	_, err = authenticator.Authenticate(nil, requestWithToken(issuer.token(t, expired, nil)))
+	require.Error(t, err)
+
+	// So are tokens for another audience.
+	otherAudience := claims
+	otherAudience.Audience = jwt.Audience{"other"}
+	_, err = authenticator.Authenticate(nil, requestWithToken(issuer.token(t, otherAudience, nil)))
+	require.Error(t, err)
+
+	// Tokens signed by another key are rejected, even if they claim to be from the issuer.
+	impostor := newStubIssuer(t)
+	_, err = authenticator.Authenticate(nil, requestWithToken(impostor.token(t, claims, nil)))
+	require.Error(t, err)
+
+	// Tokens from other issuers, and tokens that aren't JWTs, are left for other authenticators.
+	otherIssuer := claims
+	otherIssuer.Issuer = "https://other.test"
+	identity, err = authenticator.Authenticate(nil, requestWithToken(issuer.token(t, otherIssuer, nil)))
+	require.NoError(t, err)
+	require.Nil(t, identity)
+
+	identity, err = authenticator.Authenticate(nil, requestWithToken("secret"))
+	require.NoError(t, err)
+	require.Nil(t, identity)
+}
+
+// Ensures requests keep using the cached keys while a slow issuer is loading them again.
+func TestOIDCAuthenticatorSlowIssuer(t *testing.T) {
+	issuer := newStubIssuer(t)
+	authenticator := NewOIDCAuthenticator(OIDCConfig{Issuer: "https://issuer.test", JWKS: issuer.server.URL})
+
+	claims := jwt.Claims{Issuer: "https://issuer.test", Subject: "user", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	token := issuer.token(t, claims, nil)
+	_, err := authenticator.Authenticate(nil, requestWithToken(token))
+	require.NoError(t, err)
+
+	// Make the cached keys stale, and the issuer hang until the test ends.
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-unblock }))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(unblock) })
+
+	authenticator.mu.Lock()
+	authenticator.config.JWKS = slow.URL
+	authenticator.loadedAt = time.Now().Add(-2 * jwksRefreshInterval)
+	authenticator.mu.Unlock()
+
+	go func() { _, _ = authenticator.Authenticate(nil, requestWithToken(token)) }()
+	require.Eventually(t, func() bool {
+		authenticator.mu.Lock()
+		defer authenticator.mu.Unlock()
+
+		return authenticator.loading
+	}, 5*time.Second, 10*time.Millisecond)
+
+	identity, err := authenticator.Authenticate(nil, requestWithToken(token))
+	require.NoError(t, err)
+	require.Equal(t, "user", identity.Username)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared/logger"
@@ -25,23 +24,50 @@ func (e ErrInvalidHost) Unwrap() error {
 	return e.error
 }
 
-// AllowAuthenticated checks if the request is trusted by extracting access.TrustedRequest from the request context.
+// AllowAuthenticated checks if the request is trusted by extracting the request's Identity from its context.
+// Unprivileged users of the unix socket, i.e. members of the socket group other than root, may only read.
 // This handler is used as an access handler by default if AllowUntrusted is false on a rest.EndpointAction.
 func AllowAuthenticated(state *state.State, r *http.Request) response.Response {
-	trusted := r.Context().Value(request.CtxAccess)
-	if trusted == nil {
+	identity := access.GetRequestIdentity(r)
+	if identity == nil {
 		return response.Forbidden(nil)
 	}
 
-	trustedReq, ok := trusted.(access.TrustedRequest)
-	if !ok {
+	if !identity.Privileged() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return response.Forbidden(fmt.Errorf("User %q may only perform read-only operations", identity.Username))
+	}
+
+	return response.EmptySyncResponse
+}
+
+// AllowPrivileged only allows trusted requests from privileged senders. Over the unix socket, these are root and the
+// user the daemon runs as. It is used for operations that control the daemon, or expose the database.
+func AllowPrivileged(state *state.State, r *http.Request) response.Response {
+	identity := access.GetRequestIdentity(r)
+	if identity == nil {
 		return response.Forbidden(nil)
 	}
 
-	if !trustedReq.Trusted {
+	if !identity.Privileged() {
+		return response.Forbidden(fmt.Errorf("User %q is not allowed to perform this operation", identity.Username))
+	}
+
+	return response.EmptySyncResponse
+}
+
+// AllowMember only allows trusted requests from other cluster members, and from privileged senders over the unix
+// socket, who already control this member. It is used for the internal endpoints that members use to coordinate
+// with each other, which are never available to senders authenticated by other methods.
+func AllowMember(state *state.State, r *http.Request) response.Response {
+	identity := access.GetRequestIdentity(r)
+	if identity == nil {
 		return response.Forbidden(nil)
 	}
 
+	if !identity.Member() && (identity.Method != access.MethodUnix || !identity.Privileged()) {
+		return response.Forbidden(fmt.Errorf("User %q is not a cluster member", identity.Username))
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -59,25 +85,32 @@ func Authenticate(state *state.State, r *http.Request, hostAddress string, trust
 	}
 
 	// Ensure the given host address is valid.
-	hostAddrPort, err := types.ParseAddrPort(hostAddress)
+	hostPort, err := types.ParseHostPort(hostAddress)
 	if err != nil {
 		return false, fmt.Errorf("Invalid host address %q", hostAddress)
 	}
 
-	switch r.Host {
-	case hostAddrPort.String():
-		if r.TLS != nil {
-			for _, cert := range r.TLS.PeerCertificates {
-				trusted, fingerprint := util.CheckTrustState(*cert, trustedCerts, nil, false)
-				if trusted {
-					logger.Debugf("Trusting HTTP request to %q from %q with fingerprint %q", r.URL.String(), r.RemoteAddr, fingerprint)
+	// The request may be addressed to either the configured DNS name or one of the addresses it resolved to when it
+	// was configured. The request's host is never resolved, as it is chosen by the sender.
+	var resolved types.AddrPorts
+	if state.ResolvedAddress != nil && hostAddress == state.Address().URL.Host {
+		resolved = state.ResolvedAddress()
+	}
 
-					return trusted, nil
-				}
+	requestHostPort, err := types.ParseHostPort(r.Host)
+	if err != nil || !hostPort.MatchesResolved(requestHostPort, resolved) {
+		return false, ErrInvalidHost{error: fmt.Errorf("Invalid request address %q", r.Host)}
+	}
+
+	if r.TLS != nil {
+		for _, cert := range r.TLS.PeerCertificates {
+			trusted, fingerprint := util.CheckTrustState(*cert, trustedCerts, nil, false)
+			if trusted {
+				logger.Debugf("Trusting HTTP request to %q from %q with fingerprint %q", r.URL.String(), r.RemoteAddr, fingerprint)
+
+				return trusted, nil
 			}
 		}
-	default:
-		return false, ErrInvalidHost{error: fmt.Errorf("Invalid request address %q", r.Host)}
 	}
 
 	return false, nil
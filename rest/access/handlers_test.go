@@ -0,0 +1,51 @@
+package access
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/internal/rest/access"
+)
+
+func TestAccessHandlers(t *testing.T) {
+	root := &Identity{Method: MethodUnix, Credentials: &PeerCredentials{UID: uint32(os.Getuid())}}
+	user := &Identity{Method: MethodUnix, Credentials: &PeerCredentials{UID: uint32(os.Getuid()) + 1}}
+	member := &Identity{Method: MethodTLS, Username: "member"}
+	bearer := &Identity{Method: MethodBearer, Username: "bearer"}
+	oidc := &Identity{Method: MethodOIDC, Username: "oidc", Groups: []string{"admins"}}
+	client := &Identity{Method: MethodClient, Username: "client"}
+
+	cases := []struct {
+		name       string
+		identity   *Identity
+		method     string
+		authorized bool
+		privileged bool
+		member     bool
+	}{
+		{name: "Untrusted", identity: nil, method: "GET", authorized: false, privileged: false, member: false},
+		{name: "Root read", identity: root, method: "GET", authorized: true, privileged: true, member: true},
+		{name: "Root write", identity: root, method: "POST", authorized: true, privileged: true, member: true},
+		{name: "Socket group read", identity: user, method: "GET", authorized: true, privileged: false, member: false},
+		{name: "Socket group write", identity: user, method: "DELETE", authorized: false, privileged: false, member: false},
+		{name: "Cluster member write", identity: member, method: "PUT", authorized: true, privileged: true, member: true},
+		{name: "Bearer token read", identity: bearer, method: "GET", authorized: true, privileged: false, member: false},
+		{name: "Bearer token write", identity: bearer, method: "POST", authorized: false, privileged: false, member: false},
+		{name: "OIDC write", identity: oidc, method: "PUT", authorized: false, privileged: false, member: false},
+		{name: "Client certificate write", identity: client, method: "DELETE", authorized: true, privileged: true, member: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := access.SetRequestIdentity(httptest.NewRequest(c.method, "/1.0", nil), c.identity)
+
+			require.Equal(t, c.authorized, AllowAuthenticated(nil, r) == response.EmptySyncResponse)
+			require.Equal(t, c.privileged, AllowPrivileged(nil, r) == response.EmptySyncResponse)
+			require.Equal(t, c.member, AllowMember(nil, r) == response.EmptySyncResponse)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package rest
+
+import (
+	"github.com/canonical/microcluster/internal/state"
+)
+
+// HealthCheck is an additional check reported as a component of the daemon health.
+type HealthCheck = state.HealthCheck
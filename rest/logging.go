@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/logging"
+)
+
+// Logger returns the logger for the given request, which includes the request ID, the caller, and the name and role
+// of this cluster member with every message.
+func Logger(r *http.Request) logger.Logger {
+	return logging.FromContext(r.Context())
+}
+
+// RequestContext returns a copy of ctx that carries the request ID and logger of the given request.
+// Requests sent to other cluster members with the returned context will be logged there under the same request ID.
+func RequestContext(ctx context.Context, r *http.Request) context.Context {
+	return logging.Inherit(ctx, r.Context())
+}
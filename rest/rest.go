@@ -2,6 +2,7 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
@@ -38,6 +39,25 @@ type Endpoint struct {
 
 	AllowedDuringShutdown bool // Whether we should return Unavailable Error (503) if daemon is shutting down.
 	AllowedBeforeInit     bool // Whether we should return Unavailabel Error (503) if the daemon has not been initialized (is not yet part of a cluster).
+
+	RateLimit *RateLimit // Limits on the rate and concurrency of network requests to this endpoint. Unlimited if nil.
+}
+
+// RateLimit limits the requests that an endpoint will handle over the network.
+// Requests that exceed the limit are rejected with Too Many Requests (429) and a Retry-After header.
+// Requests over the local unix socket are never limited.
+type RateLimit struct {
+	// Requests is the number of requests accepted from each source address per Interval. Unlimited if zero.
+	Requests int
+
+	// Interval is the period over which Requests are accepted. Defaults to one second.
+	Interval time.Duration
+
+	// Burst is the number of requests that a source address can make at once. Defaults to Requests.
+	Burst int
+
+	// MaxConcurrent is the number of requests, from any source, that the endpoint handles at once. Unlimited if zero.
+	MaxConcurrent int
 }
 
 // Resources represents all the resources served over the same path.
@@ -0,0 +1,287 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// HostPort is a host and port pair, where the host is either a literal IP address or a DNS name.
+// Unlike AddrPort, DNS names are kept as-is and are only resolved when a connection is made, so that
+// cluster members can be addressed by a stable name even if the underlying IP changes.
+//
+// HostPort is (json/yaml).(Marshalled/Unmarshalled) to the same string form as AddrPort,
+// so records written with an AddrPort can be read back as a HostPort.
+type HostPort struct {
+	host string
+	addr netip.Addr
+	port uint16
+}
+
+// HostPorts is a defined type for a slice of HostPort. This facilitates convenience functions.
+type HostPorts []HostPort
+
+// ParseHostPort parses a "host:port" string, where host is an IPv4/IPv6 address or a DNS name, into a HostPort.
+func ParseHostPort(hostPortStr string) (HostPort, error) {
+	host, portStr, err := net.SplitHostPort(hostPortStr)
+	if err != nil {
+		return HostPort{}, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("Invalid port %q: %w", portStr, err)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err == nil {
+		return HostPort{host: addr.String(), addr: addr, port: uint16(port)}, nil
+	}
+
+	host, err = normalizeDNSName(host)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("Invalid host %q: %w", hostPortStr, err)
+	}
+
+	return HostPort{host: host, port: uint16(port)}, nil
+}
+
+// ParseHostPorts parses a list of "host:port" strings into a HostPorts.
+func ParseHostPorts(hostPortStrs []string) (HostPorts, error) {
+	var err error
+	hostPorts := make(HostPorts, len(hostPortStrs))
+	for i, hostPortStr := range hostPortStrs {
+		hostPorts[i], err = ParseHostPort(hostPortStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hostPorts, nil
+}
+
+// HostPortFromAddrPort returns a HostPort for the IP address and port of the given AddrPort.
+func HostPortFromAddrPort(addrPort AddrPort) HostPort {
+	return HostPort{host: addrPort.Addr().String(), addr: addrPort.Addr(), port: addrPort.Port()}
+}
+
+// normalizeDNSName validates the given DNS name and returns it in lowercase without any trailing dot.
+func normalizeDNSName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if len(name) < 1 || len(name) > 253 {
+		return "", fmt.Errorf("Name must be 1-253 characters long")
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) < 1 || len(label) > 63 {
+			return "", fmt.Errorf("Name labels must be 1-63 characters long")
+		}
+
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf(`Name labels must not start or end with "-" character`)
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", fmt.Errorf("Name can only contain alphanumeric, hyphen and dot characters")
+			}
+		}
+	}
+
+	return name, nil
+}
+
+// Host returns the host portion of the HostPort, which is either an IP address or a DNS name.
+func (h HostPort) Host() string {
+	return h.host
+}
+
+// Port returns the port of the HostPort.
+func (h HostPort) Port() uint16 {
+	return h.port
+}
+
+// Addr returns the IP address of the HostPort, and whether the host is a literal IP address.
+func (h HostPort) Addr() (netip.Addr, bool) {
+	return h.addr, h.addr.IsValid()
+}
+
+// IsIP returns whether the host is a literal IP address, rather than a DNS name.
+func (h HostPort) IsIP() bool {
+	return h.addr.IsValid()
+}
+
+// IsValid returns whether the HostPort has been initialized with a host.
+func (h HostPort) IsValid() bool {
+	return h.host != ""
+}
+
+// String returns the "host:port" form of the HostPort. IP addresses are formatted in the same way as AddrPort.
+func (h HostPort) String() string {
+	if h.addr.IsValid() {
+		return netip.AddrPortFrom(h.addr, h.port).String()
+	}
+
+	if h.host == "" {
+		return ""
+	}
+
+	return net.JoinHostPort(h.host, strconv.FormatUint(uint64(h.port), 10))
+}
+
+// Equal returns whether both HostPorts refer to the same host and port without resolving any DNS names.
+func (h HostPort) Equal(other HostPort) bool {
+	if h.port != other.port {
+		return false
+	}
+
+	if h.addr.IsValid() || other.addr.IsValid() {
+		return h.addr.Unmap() == other.addr.Unmap()
+	}
+
+	return h.host == other.host
+}
+
+// Matches returns whether both HostPorts refer to the same host and port.
+// If they are not Equal and either host is a DNS name, the names are resolved and their addresses are compared.
+// As it may resolve either name, it should not be used with untrusted input; see MatchesResolved.
+func (h HostPort) Matches(ctx context.Context, other HostPort) bool {
+	if h.Equal(other) {
+		return true
+	}
+
+	if h.port != other.port || (h.IsIP() && other.IsIP()) {
+		return false
+	}
+
+	addrs, err := h.Resolve(ctx)
+	if err != nil {
+		return false
+	}
+
+	otherAddrs, err := other.Resolve(ctx)
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		for _, otherAddr := range otherAddrs {
+			if addr.Addr().Unmap() == otherAddr.Addr().Unmap() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchesResolved returns whether the other HostPort is Equal to this one, or is a literal IP address among the
+// addresses this one was resolved to beforehand. No DNS names are resolved, so the other HostPort may come from
+// untrusted input, such as the Host header of a request.
+func (h HostPort) MatchesResolved(other HostPort, resolved AddrPorts) bool {
+	if h.Equal(other) {
+		return true
+	}
+
+	if h.port != other.port || !other.IsIP() {
+		return false
+	}
+
+	for _, addr := range resolved {
+		if addr.Port() == other.port && addr.Addr().Unmap() == other.addr.Unmap() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the IP addresses and port that the HostPort currently refers to.
+// Literal IP addresses are returned as-is, and DNS names are looked up with the default resolver.
+func (h HostPort) Resolve(ctx context.Context) (AddrPorts, error) {
+	if h.addr.IsValid() {
+		return AddrPorts{{AddrPort: netip.AddrPortFrom(h.addr, h.port)}}, nil
+	}
+
+	if h.host == "" {
+		return nil, fmt.Errorf("Cannot resolve empty host")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", h.host)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve host %q: %w", h.host, err)
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("No addresses found for host %q", h.host)
+	}
+
+	addrPorts := make(AddrPorts, 0, len(addrs))
+	for _, addr := range addrs {
+		addrPorts = append(addrPorts, AddrPort{AddrPort: netip.AddrPortFrom(addr.Unmap(), h.port)})
+	}
+
+	return addrPorts, nil
+}
+
+// MarshalJSON implements json.Marshaler for the HostPort type.
+func (h HostPort) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+// MarshalYAML implements yaml.Marshaler for the HostPort type.
+func (h HostPort) MarshalYAML() (any, error) {
+	return h.String(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for HostPort.
+func (h *HostPort) UnmarshalJSON(b []byte) error {
+	var hostPortStr string
+	err := json.Unmarshal(b, &hostPortStr)
+	if err != nil {
+		return err
+	}
+
+	*h, err = ParseHostPort(hostPortStr)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for HostPort.
+func (h *HostPort) UnmarshalYAML(unmarshal func(v any) error) error {
+	var hostPortStr string
+	err := unmarshal(&hostPortStr)
+	if err != nil {
+		return err
+	}
+
+	*h, err = ParseHostPort(hostPortStr)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Strings returns a string slice of the HostPorts.
+func (h HostPorts) Strings() []string {
+	hostPortStrs := make([]string, len(h))
+	for i, hostPort := range h {
+		hostPortStrs[i] = hostPort.String()
+	}
+
+	return hostPortStrs
+}
+
+// SelectRandom returns a randomly selected HostPort from HostPorts.
+func (h HostPorts) SelectRandom() HostPort {
+	return h[rand.Intn(len(h))]
+}
@@ -0,0 +1,131 @@
+package types
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHostPort(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		want      string
+		isIP      bool
+		wantError bool
+	}{
+		{"IPv4 address", "10.0.0.1:9000", "10.0.0.1:9000", true, false},
+		{"IPv6 address", "[fd42::1]:9000", "[fd42::1]:9000", true, false},
+		{"DNS name", "node1.example.com:9000", "node1.example.com:9000", false, false},
+		{"DNS name is lowercased", "Node1.Example.COM:9000", "node1.example.com:9000", false, false},
+		{"DNS name trailing dot", "node1.example.com.:9000", "node1.example.com:9000", false, false},
+		{"Single label name", "localhost:9000", "localhost:9000", false, false},
+		{"Missing port", "node1.example.com", "", false, true},
+		{"Invalid port", "node1:99999", "", false, true},
+		{"Empty host", ":9000", "", false, true},
+		{"Invalid characters", "node_1:9000", "", false, true},
+		{"Label starts with hyphen", "-node1:9000", "", false, true},
+		{"Empty label", "node1..example.com:9000", "", false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hostPort, err := ParseHostPort(c.input)
+			if c.wantError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.want, hostPort.String())
+			assert.Equal(t, c.isIP, hostPort.IsIP())
+			assert.Equal(t, uint16(9000), hostPort.Port())
+		})
+	}
+}
+
+func TestHostPortEqual(t *testing.T) {
+	cases := []struct {
+		name  string
+		a     string
+		b     string
+		equal bool
+	}{
+		{"Same address", "10.0.0.1:9000", "10.0.0.1:9000", true},
+		{"IPv4-mapped IPv6 address", "10.0.0.1:9000", "[::ffff:10.0.0.1]:9000", true},
+		{"Different port", "10.0.0.1:9000", "10.0.0.1:9001", false},
+		{"Same name", "node1:9000", "NODE1.:9000", true},
+		{"Different name", "node1:9000", "node2:9000", false},
+		{"Name and address", "node1:9000", "10.0.0.1:9000", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ParseHostPort(c.a)
+			require.NoError(t, err)
+
+			b, err := ParseHostPort(c.b)
+			require.NoError(t, err)
+
+			assert.Equal(t, c.equal, a.Equal(b))
+			assert.Equal(t, c.equal, b.Equal(a))
+		})
+	}
+}
+
+func TestHostPortMatchesResolved(t *testing.T) {
+	configured, err := ParseHostPort("node1.example.com:9000")
+	require.NoError(t, err)
+
+	resolved := AddrPorts{{AddrPort: netip.MustParseAddrPort("10.0.0.1:9000")}}
+	cases := []struct {
+		name    string
+		request string
+		matches bool
+	}{
+		{"Same name", "NODE1.example.com:9000", true},
+		{"Resolved address", "10.0.0.1:9000", true},
+		{"IPv4-mapped resolved address", "[::ffff:10.0.0.1]:9000", true},
+		{"Other address", "10.0.0.2:9000", false},
+		{"Different port", "10.0.0.1:9001", false},
+		{"Other name that may resolve to the address", "localhost:9000", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request, err := ParseHostPort(c.request)
+			require.NoError(t, err)
+
+			assert.Equal(t, c.matches, configured.MatchesResolved(request, resolved))
+		})
+	}
+}
+
+func TestHostPortJSON(t *testing.T) {
+	for _, input := range []string{"10.0.0.1:9000", "[fd42::1]:9000", "node1.example.com:9000"} {
+		hostPort, err := ParseHostPort(input)
+		require.NoError(t, err)
+
+		b, err := json.Marshal(hostPort)
+		require.NoError(t, err)
+		assert.Equal(t, `"`+input+`"`, string(b))
+
+		var out HostPort
+		require.NoError(t, json.Unmarshal(b, &out))
+		assert.True(t, hostPort.Equal(out))
+	}
+
+	// Records written as an AddrPort must be readable as a HostPort.
+	addrPort, err := ParseAddrPort("[fd42::1]:9000")
+	require.NoError(t, err)
+
+	b, err := json.Marshal(addrPort)
+	require.NoError(t, err)
+
+	var out HostPort
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, addrPort.String(), out.String())
+}
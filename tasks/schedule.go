@@ -0,0 +1,218 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a task next runs.
+type Schedule interface {
+	// Next returns the first time after the given time that the task should run, or the zero time if it should not
+	// run again.
+	Next(after time.Time) time.Time
+
+	// String describes the schedule.
+	String() string
+}
+
+// Every returns a Schedule that runs a task at a fixed interval.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+type every time.Duration
+
+// Next returns the time one interval after the given time.
+func (e every) Next(after time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+
+	return after.Add(time.Duration(e))
+}
+
+func (e every) String() string {
+	return fmt.Sprintf("every %s", time.Duration(e))
+}
+
+// cronAliases are the supported shorthands for common cron expressions.
+var cronAliases = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// cronField is the range of values allowed in a field of a cron expression.
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// cron is a Schedule parsed from a cron expression. Each field is a bitmask of the values it matches.
+type cron struct {
+	expr string
+
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// Whether the day of month and day of week fields were restricted. If both are, either may match.
+	dayOfMonthSet bool
+	dayOfWeekSet  bool
+}
+
+// Cron returns a Schedule from a standard five field cron expression: minute, hour, day of month, month, and day of
+// week. Each field is `*`, a value, a range `a-b`, or a comma separated list of those, optionally with a step `/n`.
+// Days of the week are 0-7, where both 0 and 7 are Sunday. The aliases @yearly, @monthly, @weekly, @daily and
+// @hourly are also supported. Times are matched in the location of the time passed to Next.
+func Cron(expr string) (Schedule, error) {
+	alias, ok := cronAliases[strings.TrimSpace(expr)]
+	if ok {
+		expr = alias
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("Invalid cron expression %q: Expected %d fields, got %d", expr, len(cronFields), len(fields))
+	}
+
+	masks := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		masks[i], err = parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron expression %q: %w", expr, err)
+		}
+	}
+
+	// Sunday may be given as either 0 or 7.
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return &cron{
+		expr:          expr,
+		minute:        masks[0],
+		hour:          masks[1],
+		dayOfMonth:    masks[2],
+		month:         masks[3],
+		dayOfWeek:     masks[4],
+		dayOfMonthSet: fields[2] != "*",
+		dayOfWeekSet:  fields[4] != "*",
+	}, nil
+}
+
+// parseCronField returns the bitmask of values matched by a single field of a cron expression.
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("Invalid step %q in %s field", stepPart, bounds.name)
+			}
+		}
+
+		start, end := bounds.min, bounds.max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			start, err = strconv.Atoi(startPart)
+			if err != nil {
+				return 0, fmt.Errorf("Invalid value %q in %s field", startPart, bounds.name)
+			}
+
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endPart)
+				if err != nil {
+					return 0, fmt.Errorf("Invalid value %q in %s field", endPart, bounds.name)
+				}
+			} else if hasStep {
+				// A single value with a step, e.g. 5/15, runs from that value to the end of the range.
+				end = bounds.max
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("Value %q out of range %d-%d in %s field", rangePart, bounds.min, bounds.max, bounds.name)
+		}
+
+		for i := start; i <= end; i += step {
+			mask |= 1 << i
+		}
+	}
+
+	return mask, nil
+}
+
+// Next returns the first minute after the given time that matches the cron expression.
+func (c *cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid expression matches at least once every few years, so give up on expressions like "0 0 31 2 *".
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		if c.month&(1<<uint(month)) == 0 {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.matchesDay(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay returns whether the day of the given time matches the day of month and day of week fields.
+func (c *cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if c.dayOfMonthSet && c.dayOfWeekSet {
+		return dayOfMonth || dayOfWeek
+	}
+
+	return dayOfMonth && dayOfWeek
+}
+
+func (c *cron) String() string {
+	return c.expr
+}
@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCron(t *testing.T) {
+	// Monday 1st January 2024, 10:30.
+	start := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{expr: "* * * * *", next: time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", next: time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{expr: "0 * * * *", next: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{expr: "@daily", next: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 9-17 * * *", next: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{expr: "0 8,20 * * *", next: time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 0", next: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", next: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", next: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 15 * 5", next: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 31 2 *", next: time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := Cron(test.expr)
+			require.NoError(t, err)
+			require.Equal(t, test.next, schedule.Next(start))
+		})
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Cron(expr)
+		require.Error(t, err, "Expected an error for %q", expr)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	require.Equal(t, start.Add(time.Minute), Every(time.Minute).Next(start))
+	require.True(t, Every(0).Next(start).IsZero())
+}
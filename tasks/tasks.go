@@ -0,0 +1,273 @@
+// Package tasks runs named background tasks on a schedule, either on every cluster member or only on the dqlite
+// leader.
+//
+// Tasks are registered with the Scheduler returned by State.Tasks(), usually from the OnStart hook:
+//
+//	err := s.Tasks().Register(tasks.Task{
+//		Name:       "cleanup",
+//		Schedule:   tasks.Every(time.Hour),
+//		LeaderOnly: true,
+//		Run: func(ctx context.Context) error {
+//			return cleanup(ctx, s)
+//		},
+//	})
+//
+// Leadership is checked each time a leader-only task is due, and a running leader-only task is cancelled as soon as
+// this member loses leadership, so such tasks move to the new leader automatically when leadership changes. All tasks
+// stop when the daemon shuts down.
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// ErrNotLeader is returned when triggering a leader-only task on a cluster member that is not the leader.
+var ErrNotLeader = api.StatusErrorf(http.StatusConflict, "Task can only run on the cluster leader")
+
+// Task is a named function run on a schedule.
+type Task struct {
+	// Name identifies the task, and must be unique on each cluster member.
+	Name string
+
+	// Schedule decides when the task runs, e.g. tasks.Every(time.Minute) or the result of tasks.Cron("0 * * * *").
+	Schedule Schedule
+
+	// LeaderOnly restricts the task to run only on the dqlite leader. Otherwise it runs on every cluster member.
+	LeaderOnly bool
+
+	// Run performs the task. The context is cancelled when the daemon shuts down, or for leader-only tasks when this
+	// member loses leadership.
+	Run func(ctx context.Context) error
+}
+
+// task is a registered Task and its status.
+type task struct {
+	Task
+
+	trigger chan struct{} // Receives on-demand runs.
+
+	running   bool
+	lastRun   time.Time
+	nextRun   time.Time
+	lastError error
+}
+
+// Scheduler runs registered tasks once it has been started.
+type Scheduler struct {
+	clock      clock.Clock
+	ready      func() bool
+	leadership func() (bool, <-chan struct{})
+
+	mu    sync.Mutex
+	ctx   context.Context // Set once the scheduler has started.
+	tasks map[string]*task
+	wg    sync.WaitGroup
+}
+
+// NewScheduler returns a Scheduler for tasks that only run once `ready` returns true. Leader-only tasks additionally
+// only run while `leadership` reports that this member is the leader, and are cancelled once the channel it returns
+// is closed.
+func NewScheduler(clock clock.Clock, ready func() bool, leadership func() (bool, <-chan struct{})) *Scheduler {
+	return &Scheduler{
+		clock:      clock,
+		ready:      ready,
+		leadership: leadership,
+		tasks:      map[string]*task{},
+	}
+}
+
+// Register adds a task to the scheduler. If the scheduler has already started, the task is scheduled right away.
+func (s *Scheduler) Register(t Task) error {
+	if t.Name == "" {
+		return fmt.Errorf("Task name must not be empty")
+	}
+
+	if t.Schedule == nil || t.Run == nil {
+		return fmt.Errorf("Task %q must have a schedule and a run function", t.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.tasks[t.Name]
+	if ok {
+		return fmt.Errorf("Task %q is already registered", t.Name)
+	}
+
+	registered := &task{Task: t, trigger: make(chan struct{}, 1)}
+	s.tasks[t.Name] = registered
+	if s.ctx != nil && s.ctx.Err() == nil {
+		s.start(registered)
+	}
+
+	return nil
+}
+
+// Start schedules all registered tasks, until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+
+	s.ctx = ctx
+	for _, t := range s.tasks {
+		s.start(t)
+	}
+}
+
+// Wait blocks until every task has stopped after the scheduler's context is cancelled.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// start runs the loop for a task. The caller must hold the lock.
+func (s *Scheduler) start(t *task) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(s.ctx, t)
+	}()
+}
+
+// loop runs the task each time it is due, or triggered, until the context is cancelled.
+func (s *Scheduler) loop(ctx context.Context, t *task) {
+	for {
+		next := t.Schedule.Next(s.clock.Now())
+
+		s.mu.Lock()
+		t.nextRun = next
+		s.mu.Unlock()
+
+		// A task with no next run can still be triggered.
+		var due <-chan time.Time
+		if !next.IsZero() {
+			due = s.clock.After(s.clock.Until(next))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-due:
+		case <-t.trigger:
+		}
+
+		s.run(ctx, t)
+	}
+}
+
+// run runs the task once, if this cluster member is eligible to run it.
+func (s *Scheduler) run(ctx context.Context, t *task) {
+	if !s.ready() {
+		logger.Debug("Skipping task until the database is ready", logger.Ctx{"task": t.Name})
+		return
+	}
+
+	if t.LeaderOnly {
+		leader, changed := s.leadership()
+		if !leader {
+			return
+		}
+
+		// Stop the task as soon as this member loses leadership, so that it never runs on two members at once.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			select {
+			case <-changed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	s.mu.Lock()
+	t.running = true
+	t.lastRun = s.clock.Now()
+	s.mu.Unlock()
+
+	err := t.Run(ctx)
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Task failed", logger.Ctx{"task": t.Name, "error": err})
+	}
+
+	s.mu.Lock()
+	t.running = false
+	t.lastError = err
+	s.mu.Unlock()
+}
+
+// Trigger runs the named task as soon as possible, outside of its schedule. If the task is already running, it runs
+// again once it completes. Leader-only tasks return ErrNotLeader if this cluster member is not the leader.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	s.mu.Lock()
+	t, ok := s.tasks[name]
+	started := s.ctx != nil
+	s.mu.Unlock()
+
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "Task %q not found", name)
+	}
+
+	if !started || !s.ready() {
+		return api.StatusErrorf(http.StatusServiceUnavailable, "Tasks are not running yet")
+	}
+
+	if t.LeaderOnly {
+		leader, _ := s.leadership()
+		if !leader {
+			return ErrNotLeader
+		}
+	}
+
+	select {
+	case t.trigger <- struct{}{}:
+	default:
+		// A run is already pending.
+	}
+
+	return nil
+}
+
+// Status returns the status of each registered task, sorted by name.
+func (s *Scheduler) Status() []types.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]types.Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		apiTask := types.Task{
+			Name:       t.Name,
+			Schedule:   t.Schedule.String(),
+			LeaderOnly: t.LeaderOnly,
+			Running:    t.running,
+			LastRun:    t.lastRun,
+			NextRun:    t.nextRun,
+		}
+
+		if t.lastError != nil {
+			apiTask.LastError = t.lastError.Error()
+		}
+
+		status = append(status, apiTask)
+	}
+
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+
+	return status
+}
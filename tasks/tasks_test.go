@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/microcluster/clock"
+)
+
+// fakeLeadership reports leadership as set by the test, closing its channel whenever it changes.
+type fakeLeadership struct {
+	mu      sync.Mutex
+	leader  bool
+	changed chan struct{}
+}
+
+func (l *fakeLeadership) get() (bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+
+	return l.leader, l.changed
+}
+
+func (l *fakeLeadership) set(leader bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.changed != nil {
+		close(l.changed)
+	}
+
+	l.leader = leader
+	l.changed = make(chan struct{})
+}
+
+// waitFor polls the condition until it is true, or fails the test after a second.
+func waitFor(t *testing.T, condition func() bool) {
+	require.Eventually(t, condition, time.Second, time.Millisecond)
+}
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFake(start)
+
+	leader := &fakeLeadership{}
+	s := NewScheduler(fakeClock, func() bool { return true }, leader.get)
+
+	var everyRuns, leaderRuns atomic.Int32
+	require.NoError(t, s.Register(Task{
+		Name:     "every",
+		Schedule: Every(time.Minute),
+		Run: func(ctx context.Context) error {
+			everyRuns.Add(1)
+			return errors.New("Failed")
+		},
+	}))
+
+	require.NoError(t, s.Register(Task{
+		Name:       "leader",
+		Schedule:   Every(time.Minute),
+		LeaderOnly: true,
+		Run: func(ctx context.Context) error {
+			leaderRuns.Add(1)
+			return nil
+		},
+	}))
+
+	require.Error(t, s.Register(Task{Name: "every", Schedule: Every(time.Minute), Run: func(ctx context.Context) error { return nil }}))
+
+	// Tasks can't be triggered before the scheduler starts.
+	require.Error(t, s.Trigger(context.Background(), "every"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	fakeClock.BlockUntil(2)
+
+	// Only the task for every member runs while this member is not the leader.
+	fakeClock.Advance(time.Minute)
+	waitFor(t, func() bool { return everyRuns.Load() == 1 })
+	require.ErrorIs(t, s.Trigger(ctx, "leader"), ErrNotLeader)
+
+	// Leader-only tasks run once this member becomes the leader.
+	fakeClock.BlockUntil(2)
+	leader.set(true)
+	fakeClock.Advance(time.Minute)
+	waitFor(t, func() bool { return everyRuns.Load() == 2 && leaderRuns.Load() == 1 })
+
+	// Triggered tasks run outside of their schedule.
+	require.NoError(t, s.Trigger(ctx, "leader"))
+	waitFor(t, func() bool { return leaderRuns.Load() == 2 })
+
+	status := s.Status()
+	require.Len(t, status, 2)
+	require.Equal(t, "every", status[0].Name)
+	require.Equal(t, "Failed", status[0].LastError)
+	require.Equal(t, start.Add(2*time.Minute), status[0].LastRun)
+	require.Equal(t, "every 1m0s", status[0].Schedule)
+	require.Equal(t, "leader", status[1].Name)
+	require.True(t, status[1].LeaderOnly)
+	require.Empty(t, status[1].LastError)
+
+	require.Error(t, s.Trigger(ctx, "missing"))
+
+	// All tasks stop once the context is cancelled.
+	cancel()
+	s.Wait()
+}
+
+// Ensures a running leader-only task is cancelled as soon as this member loses leadership.
+func TestSchedulerLeadershipLost(t *testing.T) {
+	fakeClock := clock.NewFake(time.Now())
+	leader := &fakeLeadership{}
+	leader.set(true)
+	s := NewScheduler(fakeClock, func() bool { return true }, leader.get)
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	require.NoError(t, s.Register(Task{
+		Name:       "leader",
+		Schedule:   Every(time.Hour),
+		LeaderOnly: true,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			cancelled.Store(true)
+
+			return ctx.Err()
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.Wait()
+	}()
+
+	s.Start(ctx)
+	require.NoError(t, s.Trigger(ctx, "leader"))
+	<-started
+
+	leader.set(false)
+	waitFor(t, cancelled.Load)
+	require.NoError(t, ctx.Err())
+	require.ErrorIs(t, s.Trigger(ctx, "leader"), ErrNotLeader)
+}